@@ -0,0 +1,176 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// 限流、定时调度这类代码都依赖时间，直接用 time 包测试时只能真的 sleep，又慢又不稳定
+// 把时间抽象成 Clock 接口，生产环境用 New() 返回的真实时钟，测试时用 Fake 手动拨动时间
+
+// Clock 时钟
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTimer(d time.Duration) Timer
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// Timer 对应 *time.Timer，time.Timer 的 C 是字段，接口里只能换成方法
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// New 返回真实的时钟
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// Fake 是测试用的时钟，只有调用 Advance 或 Set 时时间才会前进，到期的 Timer 随之触发
+type Fake struct {
+	mu     sync.Mutex
+	cond   *sync.Cond // Timer 的数量变化时通知 BlockUntil
+	now    time.Time
+	timers map[*fakeTimer]struct{}
+}
+
+// NewFake 创建一个测试用的时钟，初始时间是 now
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now, timers: make(map[*fakeTimer]struct{})}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now 返回当前的时间
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since 返回从 t 到现在经过的时间
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// NewTimer 创建一个 d 之后触发的 Timer
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// After 等同于 NewTimer(d).C()
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep 阻塞直到别的 goroutine 把时间拨过 d
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// Advance 把时间往前拨 d，触发到期的 Timer
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set 把时间设置为 t，触发到期的 Timer，t 早于当前时间时什么也不做
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t.Before(f.now) {
+		return
+	}
+	f.now = t
+	for timer := range f.timers {
+		if !timer.when.After(t) {
+			delete(f.timers, timer)
+			select {
+			case timer.c <- t:
+			default:
+			}
+		}
+	}
+	f.cond.Broadcast()
+}
+
+// Timers 返回还没有触发的 Timer 数量
+func (f *Fake) Timers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil 阻塞直到还没有触发的 Timer 数量为 n
+// 测试中用来确认被测的 goroutine 已经开始等待，再拨动时间
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) != n {
+		f.cond.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock *Fake
+	c     chan time.Time
+	when  time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, active := f.timers[t]
+	delete(f.timers, t)
+	f.cond.Broadcast()
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, active := f.timers[t]
+	t.when = f.now.Add(d)
+	if d <= 0 {
+		// 和 time.Timer 一样，d 小于等于 0 时立即触发
+		delete(f.timers, t)
+		select {
+		case t.c <- f.now:
+		default:
+		}
+	} else {
+		f.timers[t] = struct{}{}
+	}
+	f.cond.Broadcast()
+	return active
+}
@@ -0,0 +1,67 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRealClock(t *testing.T) {
+	c := New()
+	start := c.Now()
+	<-c.After(10 * time.Millisecond)
+	assert.GreaterOrEqual(t, c.Since(start), 10*time.Millisecond)
+
+	timer := c.NewTimer(time.Hour)
+	assert.True(t, timer.Stop())
+}
+
+func TestFakeTimer(t *testing.T) {
+	start := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	t1 := f.NewTimer(time.Second)
+	t2 := f.NewTimer(2 * time.Second)
+	assert.Equal(t, 2, f.Timers())
+
+	f.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-t1.C())
+	select {
+	case <-t2.C():
+		t.Fatal("t2 fired too early")
+	default:
+	}
+
+	// 停止后不会再触发
+	assert.True(t, t2.Stop())
+	assert.False(t, t2.Stop())
+	f.Advance(time.Hour)
+	select {
+	case <-t2.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+
+	// 重置后重新计时
+	assert.False(t, t2.Reset(time.Second))
+	f.Advance(time.Second)
+	<-t2.C()
+	assert.Equal(t, 0, f.Timers())
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(time.Now())
+
+	done := make(chan struct{})
+	go func() {
+		f.Sleep(time.Minute)
+		close(done)
+	}()
+
+	// 等到 Sleep 开始等待后再拨动时间
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	<-done
+	assert.Equal(t, time.Minute, f.Since(f.Now().Add(-time.Minute)))
+}
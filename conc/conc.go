@@ -1,25 +1,31 @@
 package conc
 
 import (
+	"context"
 	"fmt"
-	"github.com/sourcegraph/conc"
 	"io"
 )
 
-// https://github.com/sourcegraph/conc
-// 更优雅的并发工具，功能丰富，调用更优雅
-// todo 目前尚未发布 1.0 版本，不建议在生产环境中使用，等 1.0 发布后完善此部分
+// 之前用 https://github.com/sourcegraph/conc，还没有发布 1.0，不建议在生产环境中使用
+// 现在用 Nursery 实现同样的功能：goroutine 只能在作用域里启动，panic 带着调用栈传播到调用方
 
 func ConcDemo() {
-	var wg conc.WaitGroup
-	wg.Go(func() {
-		// do something
-		panic(io.EOF)
-	})
+	defer func() {
+		if pe, ok := recover().(*PanicError); ok {
+			fmt.Println("recovered:", pe.Value)
+		}
+	}()
 
-	wg.Go(func() {
-		fmt.Println("hello")
-	})
+	_ = Run(context.Background(), func(n *Nursery) error {
+		n.Go(func(ctx context.Context) error {
+			// do something
+			panic(io.EOF)
+		})
 
-	wg.WaitAndRecover()
+		n.Go(func(ctx context.Context) error {
+			fmt.Println("hello")
+			return nil
+		})
+		return nil
+	})
 }
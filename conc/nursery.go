@@ -0,0 +1,119 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"concurrence/internal/panics"
+)
+
+// Nursery 结构化并发：goroutine 只能在 Run 打开的作用域里启动，Run 返回时所有 goroutine 一定都已经结束
+//   - 第一个错误或者 panic 取消作用域的 ctx，其它任务通过 ctx 感知并尽快返回
+//   - 任务 panic 时，等所有任务结束后在调用 Run 的 goroutine 中重新 panic 一个带调用栈的 *PanicError
+//   - 嵌套的作用域用外层的 ctx 创建，外层取消时内层也被取消；内层的 panic 沿着 Run 一层层向外传播
+//   - Run 返回之后 Nursery 关闭，再调用 Go 会 panic，不会有逃逸出作用域的 goroutine
+
+// PanicError 任务 panic 时的值和调用栈
+type PanicError = panics.Error
+
+// Nursery 任务的作用域，只能通过 Run 得到
+type Nursery struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	running  int
+	closed   bool
+	errs     []error
+	panicked *PanicError
+}
+
+// Run 创建一个作用域并执行 body，body 中可以用 n.Go 启动任务
+// body 和所有任务都返回之后 Run 才返回，只有一个错误时返回这个错误，否则返回所有错误的 errors.Join
+func Run(ctx context.Context, body func(n *Nursery) error) error {
+	n := &Nursery{}
+	n.cond = sync.NewCond(&n.mu)
+	n.ctx, n.cancel = context.WithCancelCause(ctx)
+	defer n.cancel(nil)
+
+	n.run(func() error { return body(n) })
+	return n.wait()
+}
+
+// Context 返回作用域的 ctx，出错、panic 或者外层的 ctx 被取消时被取消
+func (n *Nursery) Context() context.Context {
+	return n.ctx
+}
+
+// Go 在新的 goroutine 中执行 f，f 返回错误会取消作用域
+// 任务中也可以调用 Go 启动新的任务，Run 返回之后调用 Go 会 panic
+func (n *Nursery) Go(f func(ctx context.Context) error) {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		panic("conc: Go called on a closed Nursery")
+	}
+	n.running++
+	n.mu.Unlock()
+
+	go func() {
+		defer n.finish()
+		n.run(func() error { return f(n.ctx) })
+	}()
+}
+
+// run 执行 f，记录错误和 panic
+func (n *Nursery) run(f func() error) {
+	defer func() {
+		if v := recover(); v != nil {
+			pe, ok := v.(*PanicError)
+			if !ok {
+				// 嵌套的 Run 重新 panic 的已经是 *PanicError，保留最里层的调用栈
+				pe = panics.New(v)
+			}
+			n.mu.Lock()
+			if n.panicked == nil {
+				n.panicked = pe
+			}
+			n.mu.Unlock()
+			n.cancel(pe)
+		}
+	}()
+
+	if err := f(); err != nil {
+		n.mu.Lock()
+		n.errs = append(n.errs, err)
+		n.mu.Unlock()
+		n.cancel(err)
+	}
+}
+
+func (n *Nursery) finish() {
+	n.mu.Lock()
+	n.running--
+	if n.running == 0 {
+		n.cond.Broadcast()
+	}
+	n.mu.Unlock()
+}
+
+// wait 等待所有任务结束后关闭 Nursery，有任务 panic 时重新 panic
+func (n *Nursery) wait() error {
+	n.mu.Lock()
+	for n.running > 0 {
+		n.cond.Wait()
+	}
+	n.closed = true
+	errs, panicked := n.errs, n.panicked
+	n.mu.Unlock()
+
+	if panicked != nil {
+		panic(panicked)
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,142 @@
+package conc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNursery(t *testing.T) {
+	var n atomic.Int32
+	err := Run(context.Background(), func(nursery *Nursery) error {
+		for range 10 {
+			nursery.Go(func(ctx context.Context) error {
+				// 任务中也可以启动新的任务
+				nursery.Go(func(ctx context.Context) error {
+					time.Sleep(time.Millisecond)
+					n.Add(1)
+					return nil
+				})
+				n.Add(1)
+				return nil
+			})
+		}
+		return nil
+	})
+	// Run 返回时所有任务都已经结束
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), n.Load())
+}
+
+func TestNurseryError(t *testing.T) {
+	errFail := errors.New("fail")
+	var cause error
+	err := Run(context.Background(), func(n *Nursery) error {
+		n.Go(func(ctx context.Context) error { return errFail })
+		n.Go(func(ctx context.Context) error {
+			// 第一个错误取消作用域
+			<-ctx.Done()
+			cause = context.Cause(ctx)
+			return ctx.Err()
+		})
+		return nil
+	})
+	assert.ErrorIs(t, err, errFail)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, errFail, cause)
+
+	// body 返回的错误也会取消作用域
+	err = Run(context.Background(), func(n *Nursery) error {
+		n.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		return errFail
+	})
+	assert.Equal(t, errFail, err)
+}
+
+func TestNurseryPanic(t *testing.T) {
+	var cancelled atomic.Bool
+	defer func() {
+		pe, ok := recover().(*PanicError)
+		assert.True(t, ok)
+		assert.Equal(t, "boom", pe.Value)
+		// 调用栈是 panic 的位置
+		assert.Contains(t, string(pe.Stack), "nursery_test.go")
+		// 重新 panic 之前其它任务已经结束
+		assert.True(t, cancelled.Load())
+	}()
+
+	_ = Run(context.Background(), func(n *Nursery) error {
+		n.Go(func(ctx context.Context) error { panic("boom") })
+		n.Go(func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(time.Millisecond)
+			cancelled.Store(true)
+			return nil
+		})
+		return nil
+	})
+	t.Fatal("Run should panic")
+}
+
+func TestNurseryNested(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	// 外层的 ctx 被取消时，嵌套的作用域也被取消
+	err := Run(ctx, func(outer *Nursery) error {
+		outer.Go(func(ctx context.Context) error {
+			return Run(ctx, func(inner *Nursery) error {
+				inner.Go(func(ctx context.Context) error {
+					close(started)
+					<-ctx.Done()
+					return ctx.Err()
+				})
+				return nil
+			})
+		})
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 内层的 panic 传播到外层，保留原来的调用栈
+	defer func() {
+		pe, ok := recover().(*PanicError)
+		assert.True(t, ok)
+		assert.Equal(t, "inner", pe.Value)
+	}()
+	_ = Run(context.Background(), func(outer *Nursery) error {
+		outer.Go(func(ctx context.Context) error {
+			return Run(ctx, func(inner *Nursery) error {
+				inner.Go(func(ctx context.Context) error { panic("inner") })
+				return nil
+			})
+		})
+		return nil
+	})
+	t.Fatal("Run should panic")
+}
+
+func TestNurseryClosed(t *testing.T) {
+	var escaped *Nursery
+	assert.NoError(t, Run(context.Background(), func(n *Nursery) error {
+		escaped = n
+		return nil
+	}))
+
+	// Run 返回之后作用域已经关闭，ctx 被取消
+	assert.Panics(t, func() {
+		escaped.Go(func(ctx context.Context) error { return nil })
+	})
+	assert.Error(t, escaped.Context().Err())
+}
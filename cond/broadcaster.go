@@ -0,0 +1,57 @@
+package cond
+
+import (
+	"context"
+	"sync"
+)
+
+// Broadcaster 把一个值广播给所有等待者，相当于带值的 Broadcast
+// 常用于配置热更新、leader 变更这类"所有人都要知道最新值"的通知
+// 等待者只保证拿到唤醒时的最新值，两次 Wait 之间的多次广播只能看到最后一次
+
+// Broadcaster 广播器
+type Broadcaster[T any] struct {
+	mu    sync.Mutex
+	cond  *Cond
+	value T
+	gen   uint64 // 每次广播加 1
+}
+
+// NewBroadcaster 创建一个广播器
+func NewBroadcaster[T any]() *Broadcaster[T] {
+	b := &Broadcaster[T]{}
+	b.cond = NewCond(&b.mu)
+	return b
+}
+
+// Broadcast 广播 v，唤醒所有正在等待的 Wait
+func (b *Broadcaster[T]) Broadcast(v T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.value = v
+	b.gen++
+	b.cond.Broadcast()
+}
+
+// Wait 阻塞直到下一次广播或 ctx 被取消，返回广播的值
+func (b *Broadcaster[T]) Wait(ctx context.Context) (T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	gen := b.gen
+	for b.gen == gen {
+		if err := b.cond.WaitContext(ctx); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+	return b.value, nil
+}
+
+// Last 返回最近一次广播的值，ok 为 false 表示还没有广播过
+func (b *Broadcaster[T]) Last() (v T, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.value, b.gen > 0
+}
@@ -0,0 +1,41 @@
+package cond
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster[string]()
+	_, ok := b.Last()
+	assert.False(t, ok)
+
+	// 所有等待者都拿到广播的值
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := b.Wait(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "v1", v)
+		}()
+	}
+	assert.Eventually(t, func() bool { return b.cond.waiting() == 5 }, time.Second, time.Millisecond)
+	b.Broadcast("v1")
+	wg.Wait()
+
+	v, ok := b.Last()
+	assert.True(t, ok)
+	assert.Equal(t, "v1", v)
+
+	// Wait 等待的是下一次广播
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := b.Wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,91 @@
+package cond
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// sync.Cond 的 Wait 不能取消也不能超时，Demo2、Demo3 里只能用 time.Sleep 凑时间
+// Cond 的用法和 sync.Cond 一样，另外提供了 WaitContext 和 WaitTimeout
+// 实现上和 runtime 的 notifyList 一样，每个等待者按调用 Wait 的顺序排队，Signal 唤醒排在最前面的
+// 不管是被唤醒、被取消还是超时，返回前都会重新获取锁 L，调用者不需要区分
+
+// Cond 支持取消和超时的条件变量
+type Cond struct {
+	L sync.Locker
+
+	mu      sync.Mutex // 保护 waiters
+	waiters list.List  // 等待者队列，元素是 chan struct{}，唤醒时关闭
+}
+
+// NewCond 创建一个条件变量
+func NewCond(l sync.Locker) *Cond {
+	return &Cond{L: l}
+}
+
+// Wait 和 sync.Cond.Wait 一样，调用前必须持有 c.L，阻塞直到被 Signal 或 Broadcast 唤醒
+func (c *Cond) Wait() {
+	_ = c.WaitContext(context.Background())
+}
+
+// WaitContext 调用前必须持有 c.L，阻塞直到被唤醒或者 ctx 被取消，返回时一定持有 c.L
+// 被唤醒返回 nil，ctx 被取消返回 ctx.Err()
+// 取消和唤醒同时发生时算被唤醒，这个唤醒不会丢失
+func (c *Cond) WaitContext(ctx context.Context) error {
+	// 先进队列再释放锁：释放锁之后别的 goroutine 修改条件并 Signal，一定能唤醒到这里
+	ch := make(chan struct{})
+	c.mu.Lock()
+	elem := c.waiters.PushBack(ch)
+	c.mu.Unlock()
+
+	c.L.Unlock()
+	defer c.L.Lock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		select {
+		case <-ch:
+			// 被取消的同时被唤醒了，已经不在队列里
+			return nil
+		default:
+		}
+		c.waiters.Remove(elem)
+		return ctx.Err()
+	}
+}
+
+// WaitTimeout 调用前必须持有 c.L，阻塞直到被唤醒或者超时，返回时一定持有 c.L
+// 被唤醒返回 true，超时返回 false
+func (c *Cond) WaitTimeout(d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return c.WaitContext(ctx) == nil
+}
+
+// Signal 唤醒等待最久的一个等待者，调用时可以不持有 c.L
+func (c *Cond) Signal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if front := c.waiters.Front(); front != nil {
+		c.waiters.Remove(front)
+		close(front.Value.(chan struct{}))
+	}
+}
+
+// Broadcast 唤醒所有等待者，调用时可以不持有 c.L
+func (c *Cond) Broadcast() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.waiters.Front(); e != nil; e = e.Next() {
+		close(e.Value.(chan struct{}))
+	}
+	c.waiters.Init()
+}
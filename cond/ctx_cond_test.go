@@ -0,0 +1,145 @@
+package cond
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (c *Cond) waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.waiters.Len()
+}
+
+func TestCondSignalOrder(t *testing.T) {
+	var mu sync.Mutex
+	c := NewCond(&mu)
+
+	// 按调用 Wait 的顺序排队
+	woken := make(chan int, 5)
+	for i := range 5 {
+		go func() {
+			mu.Lock()
+			c.Wait()
+			woken <- i
+			mu.Unlock()
+		}()
+		assert.Eventually(t, func() bool { return c.waiting() == i+1 }, time.Second, time.Millisecond)
+	}
+
+	// Signal 依次唤醒等待最久的
+	for i := range 5 {
+		c.Signal()
+		assert.Equal(t, i, <-woken)
+	}
+}
+
+func TestCondBroadcast(t *testing.T) {
+	var mu sync.Mutex
+	c := NewCond(&mu)
+	ready := false
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			for !ready {
+				c.Wait()
+			}
+			mu.Unlock()
+		}()
+	}
+	assert.Eventually(t, func() bool { return c.waiting() == 10 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	ready = true
+	mu.Unlock()
+	c.Broadcast()
+	wg.Wait()
+	assert.Equal(t, 0, c.waiting())
+}
+
+func TestCondWaitContext(t *testing.T) {
+	var mu sync.Mutex
+	c := NewCond(&mu)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	mu.Lock()
+	err := c.WaitContext(ctx)
+	// 超时返回时也重新持有锁
+	assert.False(t, mu.TryLock())
+	mu.Unlock()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, c.waiting())
+
+	mu.Lock()
+	assert.False(t, c.WaitTimeout(10*time.Millisecond))
+	mu.Unlock()
+
+	// 被取消的等待者离开队列，Signal 唤醒下一个
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		errs <- c.WaitContext(ctx2)
+	}()
+	assert.Eventually(t, func() bool { return c.waiting() == 1 }, time.Second, time.Millisecond)
+	woken := make(chan bool, 1)
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+		woken <- c.WaitTimeout(time.Second)
+	}()
+	assert.Eventually(t, func() bool { return c.waiting() == 2 }, time.Second, time.Millisecond)
+
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	c.Signal()
+	assert.True(t, <-woken)
+}
+
+func TestCondRace(t *testing.T) {
+	// 生产者消费者，用 go test -race 检查，取消和唤醒交替发生时不丢失唤醒
+	var mu sync.Mutex
+	c := NewCond(&mu)
+	queue := 0
+	const total = 1000
+
+	var wg sync.WaitGroup
+	consumed := make(chan struct{}, total)
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for len(consumed) < total {
+				if queue == 0 {
+					// 短超时，频繁地在取消和唤醒之间竞争
+					c.WaitTimeout(time.Millisecond)
+					continue
+				}
+				queue--
+				consumed <- struct{}{}
+			}
+		}()
+	}
+
+	for range total {
+		mu.Lock()
+		queue++
+		mu.Unlock()
+		c.Signal()
+	}
+	wg.Wait()
+	assert.Equal(t, 0, queue)
+}
@@ -0,0 +1,104 @@
+package cond
+
+import (
+	"context"
+	"sync"
+)
+
+// 事件，对应 C# 的 ManualResetEvent 和 AutoResetEvent
+// ManualResetEvent 像一扇门：Set 打开门，所有等待者都通过，之后来的也直接通过，直到 Reset 关上门
+// AutoResetEvent 像一个闸机：Set 只放过一个等待者，放过之后自动关上；没有等待者时保持打开，直到有一个通过
+
+// ManualResetEvent 手动重置的事件
+type ManualResetEvent struct {
+	mu     sync.Mutex
+	cond   *Cond
+	signal bool
+}
+
+// NewManualResetEvent 创建一个事件，signaled 为 true 时初始是打开的
+func NewManualResetEvent(signaled bool) *ManualResetEvent {
+	e := &ManualResetEvent{signal: signaled}
+	e.cond = NewCond(&e.mu)
+	return e
+}
+
+// Set 打开事件，唤醒所有等待者
+func (e *ManualResetEvent) Set() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.signal = true
+	e.cond.Broadcast()
+}
+
+// Reset 关闭事件，之后的 Wait 会阻塞
+func (e *ManualResetEvent) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.signal = false
+}
+
+// IsSet 事件是否打开
+func (e *ManualResetEvent) IsSet() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.signal
+}
+
+// Wait 阻塞直到事件打开或 ctx 被取消
+func (e *ManualResetEvent) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for !e.signal {
+		if err := e.cond.WaitContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AutoResetEvent 自动重置的事件
+type AutoResetEvent struct {
+	mu     sync.Mutex
+	cond   *Cond
+	signal bool
+}
+
+// NewAutoResetEvent 创建一个事件，signaled 为 true 时初始是打开的
+func NewAutoResetEvent(signaled bool) *AutoResetEvent {
+	e := &AutoResetEvent{signal: signaled}
+	e.cond = NewCond(&e.mu)
+	return e
+}
+
+// Set 打开事件，放过一个等待者；已经打开时什么也不做，多次 Set 不会累计
+func (e *AutoResetEvent) Set() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.signal = true
+	e.cond.Signal()
+}
+
+// Reset 关闭事件
+func (e *AutoResetEvent) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.signal = false
+}
+
+// Wait 阻塞直到事件打开或 ctx 被取消，通过后事件自动关闭
+func (e *AutoResetEvent) Wait(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for !e.signal {
+		if err := e.cond.WaitContext(ctx); err != nil {
+			return err
+		}
+	}
+	e.signal = false
+	return nil
+}
@@ -0,0 +1,66 @@
+package cond
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManualResetEvent(t *testing.T) {
+	e := NewManualResetEvent(false)
+
+	// 打开后所有等待者都通过
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, e.Wait(context.Background()))
+		}()
+	}
+	e.Set()
+	wg.Wait()
+
+	// 之后来的也直接通过
+	assert.True(t, e.IsSet())
+	assert.NoError(t, e.Wait(context.Background()))
+
+	e.Reset()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestAutoResetEvent(t *testing.T) {
+	e := NewAutoResetEvent(false)
+
+	// 每次 Set 只放过一个等待者
+	var passed atomic.Int32
+	for range 3 {
+		go func() {
+			if e.Wait(context.Background()) == nil {
+				passed.Add(1)
+			}
+		}()
+	}
+	assert.Eventually(t, func() bool { return e.cond.waiting() == 3 }, time.Second, time.Millisecond)
+
+	e.Set()
+	assert.Eventually(t, func() bool { return passed.Load() == 1 }, time.Second, time.Millisecond)
+	e.Set()
+	assert.Eventually(t, func() bool { return passed.Load() == 2 }, time.Second, time.Millisecond)
+	e.Set()
+	assert.Eventually(t, func() bool { return passed.Load() == 3 }, time.Second, time.Millisecond)
+
+	// 没有等待者时保持打开，放过一个后自动关闭
+	e.Set()
+	e.Set() // 不会累计
+	assert.NoError(t, e.Wait(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,58 @@
+package cond
+
+import (
+	"context"
+	"sync"
+)
+
+// Demo 里"裁判员等 10 个运动员准备就绪"其实就是一个倒计时门闩（CountDownLatch）：
+// 计数从 n 开始，每个运动员 CountDown 一次，裁判员 Await 直到计数为 0
+// 和 WaitGroup 的区别是计数只能减不能加，计数到 0 之后门闩一直打开，不能重用
+
+// CountDownLatch 倒计时门闩
+type CountDownLatch struct {
+	mu    sync.Mutex
+	cond  *Cond
+	count int
+}
+
+// NewCountDownLatch 创建一个计数为 count 的门闩
+func NewCountDownLatch(count int) *CountDownLatch {
+	l := &CountDownLatch{count: max(count, 0)}
+	l.cond = NewCond(&l.mu)
+	return l
+}
+
+// CountDown 计数减 1，减到 0 时唤醒所有等待者，计数已经是 0 时什么也不做
+func (l *CountDownLatch) CountDown() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		l.cond.Broadcast()
+	}
+}
+
+// Await 阻塞直到计数为 0 或 ctx 被取消
+func (l *CountDownLatch) Await(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.count > 0 {
+		if err := l.cond.WaitContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count 返回当前的计数
+func (l *CountDownLatch) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.count
+}
@@ -0,0 +1,38 @@
+package cond
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountDownLatch(t *testing.T) {
+	// 裁判员等 10 个运动员准备就绪
+	l := NewCountDownLatch(10)
+	for range 10 {
+		go func() {
+			time.Sleep(time.Millisecond)
+			l.CountDown()
+		}()
+	}
+
+	assert.NoError(t, l.Await(context.Background()))
+	assert.Equal(t, 0, l.Count())
+
+	// 计数到 0 之后一直打开
+	l.CountDown()
+	assert.Equal(t, 0, l.Count())
+	assert.NoError(t, l.Await(context.Background()))
+}
+
+func TestCountDownLatchCancel(t *testing.T) {
+	l := NewCountDownLatch(2)
+	l.CountDown()
+	assert.Equal(t, 1, l.Count())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Await(ctx), context.DeadlineExceeded)
+}
@@ -0,0 +1,432 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+	"concurrence/group/schedgroup"
+	"concurrence/internal/panics"
+)
+
+// Cron 按 cron 表达式周期执行任务，任务的触发交给 schedgroup.Scheduler，在它的 worker 上执行
+// 每次触发时先安排下一次，再执行任务，任务执行的时间不影响下一次触发的时间
+//
+// 上一次还没执行完又到了执行时间（overlap）时：
+//   - OverlapAllow 同时执行，受 worker 数量限制，默认
+//   - OverlapSkip 跳过这一次
+//   - OverlapQueue 排队，上一次执行完之后马上执行
+//
+// 进程暂停、时钟跳变或者 worker 都在忙时会错过执行时间（missed run），触发时发现下一次的时间已经过去：
+//   - CatchUpNone 错过的都不执行，从现在开始算下一次，默认
+//   - CatchUpOnce 错过的合并成一次，马上执行
+//   - CatchUpAll 错过的每一次都补上
+//
+// 每个任务保留最近几次执行的记录，用 Entries/Entry 查看
+
+// Job 任务，ctx 在 Cron 停止时被取消
+type Job func(ctx context.Context) error
+
+// EntryID 任务的编号
+type EntryID int
+
+// OverlapPolicy 上一次还没执行完时的处理方式
+type OverlapPolicy int
+
+const (
+	OverlapAllow OverlapPolicy = iota
+	OverlapSkip
+	OverlapQueue
+)
+
+// CatchUpPolicy 错过执行时间时的处理方式
+type CatchUpPolicy int
+
+const (
+	CatchUpNone CatchUpPolicy = iota
+	CatchUpOnce
+	CatchUpAll
+)
+
+// RunStatus 一次执行的结果
+type RunStatus int
+
+const (
+	RunSucceeded RunStatus = iota
+	RunFailed
+	RunSkipped // 因为 OverlapSkip 跳过
+)
+
+func (s RunStatus) String() string {
+	switch s {
+	case RunSucceeded:
+		return "succeeded"
+	case RunFailed:
+		return "failed"
+	case RunSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("RunStatus(%d)", int(s))
+}
+
+// Run 一次执行的记录
+type Run struct {
+	Scheduled time.Time // 按表达式计算的执行时间，不包括 jitter
+	Started   time.Time
+	Finished  time.Time
+	Status    RunStatus
+	Err       error
+}
+
+// Entry 任务的快照
+type Entry struct {
+	ID      EntryID
+	Name    string
+	Spec    string
+	Next    time.Time // 下一次执行时间，不包括 jitter
+	Running int       // 正在执行的数量
+	Queued  int       // OverlapQueue 时排队等待的数量
+	Missed  int       // 错过并且没有补上的次数
+	History []Run     // 最近几次执行的记录，从旧到新
+}
+
+// Option Cron 的选项
+type Option func(*options)
+
+type options struct {
+	loc     *time.Location
+	clock   clock.Clock
+	workers int
+}
+
+// WithLocation 表达式没有指定时区时使用的时区，默认 time.Local
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.loc = loc
+	}
+}
+
+// WithClock 设置使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithWorkers 执行任务的 worker 数量，默认 GOMAXPROCS
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// JobOption 任务的选项
+type JobOption func(*entry)
+
+// WithName 任务的名字
+func WithName(name string) JobOption {
+	return func(e *entry) {
+		e.name = name
+	}
+}
+
+// WithOverlap 上一次还没执行完时的处理方式
+func WithOverlap(p OverlapPolicy) JobOption {
+	return func(e *entry) {
+		e.overlap = p
+	}
+}
+
+// WithCatchUp 错过执行时间时的处理方式
+func WithCatchUp(p CatchUpPolicy) JobOption {
+	return func(e *entry) {
+		e.catchUp = p
+	}
+}
+
+// WithJitter 每次执行随机推迟 [0, d)，避免大量任务同时执行
+func WithJitter(d time.Duration) JobOption {
+	return func(e *entry) {
+		e.jitter = d
+	}
+}
+
+// WithHistory 保留最近 n 次执行的记录，默认 10
+func WithHistory(n int) JobOption {
+	return func(e *entry) {
+		e.historySize = n
+	}
+}
+
+// Cron 定时任务
+type Cron struct {
+	loc    *time.Location
+	clock  clock.Clock
+	sched  *schedgroup.Scheduler
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	nextID  EntryID
+	entries map[EntryID]*entry
+}
+
+type entry struct {
+	id          EntryID
+	name        string
+	spec        string
+	schedule    Schedule
+	job         Job
+	overlap     OverlapPolicy
+	catchUp     CatchUpPolicy
+	jitter      time.Duration
+	historySize int
+
+	mu      sync.Mutex
+	task    *schedgroup.Task
+	next    time.Time
+	running int
+	queue   []time.Time // 排队等待执行的时间
+	missed  int
+	history []Run
+	removed bool
+}
+
+// New 创建一个 Cron，马上开始调度
+func New(opts ...Option) *Cron {
+	o := options{loc: time.Local, clock: clock.New(), workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Cron{
+		loc:     o.loc,
+		clock:   o.clock,
+		sched:   schedgroup.NewScheduler(ctx, schedgroup.WithWorkers(o.workers), schedgroup.WithClock(o.clock)),
+		ctx:     ctx,
+		cancel:  cancel,
+		entries: make(map[EntryID]*entry),
+	}
+}
+
+// Add 添加一个任务
+func (c *Cron) Add(spec string, job Job, opts ...JobOption) (EntryID, error) {
+	schedule, err := ParseInLocation(spec, c.loc)
+	if err != nil {
+		return 0, err
+	}
+	return c.AddSchedule(schedule, job, append([]JobOption{func(e *entry) { e.spec = spec }}, opts...)...), nil
+}
+
+// AddSchedule 按自定义的 Schedule 添加一个任务
+func (c *Cron) AddSchedule(schedule Schedule, job Job, opts ...JobOption) EntryID {
+	e := &entry{schedule: schedule, job: job, historySize: 10}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	c.mu.Lock()
+	c.nextID++
+	e.id = c.nextID
+	c.entries[e.id] = e
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	now := c.clock.Now()
+	c.scheduleNext(e, now, now)
+	e.mu.Unlock()
+	return e.id
+}
+
+// Remove 删除任务，正在执行的不受影响，排队等待的不再执行，删除成功返回 true
+func (c *Cron) Remove(id EntryID) bool {
+	c.mu.Lock()
+	e, ok := c.entries[id]
+	delete(c.entries, id)
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.removed = true
+	e.queue = nil
+	if e.task != nil {
+		e.task.Cancel()
+	}
+	return true
+}
+
+// Entry 返回任务的快照
+func (c *Cron) Entry(id EntryID) (Entry, bool) {
+	c.mu.Lock()
+	e, ok := c.entries[id]
+	c.mu.Unlock()
+	if !ok {
+		return Entry{}, false
+	}
+	return e.snapshot(), true
+}
+
+// Entries 返回所有任务的快照，按编号排序
+func (c *Cron) Entries() []Entry {
+	c.mu.Lock()
+	entries := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	c.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+	snapshots := make([]Entry, len(entries))
+	for i, e := range entries {
+		snapshots[i] = e.snapshot()
+	}
+	return snapshots
+}
+
+// Stop 停止调度，取消正在执行的任务的 ctx 并等待它们返回
+func (c *Cron) Stop() {
+	c.cancel()
+	c.sched.Stop()
+}
+
+// scheduleNext 安排 prev 之后的下一次执行，调用时需要持有 e.mu
+func (c *Cron) scheduleNext(e *entry, prev, now time.Time) {
+	next := e.schedule.Next(prev)
+	if next.IsZero() {
+		e.next, e.task = time.Time{}, nil
+		return
+	}
+
+	if !next.After(now) {
+		switch e.catchUp {
+		case CatchUpAll:
+			// 已经过去的时间会马上执行，执行时再安排下一次，直到追上
+		case CatchUpOnce:
+			// 错过的合并成现在的一次
+			e.missed += countUntil(e.schedule, next, now) - 1
+			next = now
+		default:
+			e.missed += countUntil(e.schedule, next, now)
+			next = e.schedule.Next(now)
+			if next.IsZero() {
+				e.next, e.task = time.Time{}, nil
+				return
+			}
+		}
+	}
+
+	at := next
+	if e.jitter > 0 {
+		at = at.Add(rand.N(e.jitter))
+	}
+	e.next = next
+	e.task = c.sched.Schedule(at, func() { c.trigger(e, next) })
+}
+
+// countUntil 返回从 first 开始到 now 为止的执行次数
+func countUntil(s Schedule, first, now time.Time) int {
+	n := 0
+	for t := first; !t.IsZero() && !t.After(now); t = s.Next(t) {
+		n++
+	}
+	return n
+}
+
+// trigger 在 scheduler 的 worker 上执行
+func (c *Cron) trigger(e *entry, scheduled time.Time) {
+	e.mu.Lock()
+	if e.removed {
+		e.mu.Unlock()
+		return
+	}
+	now := c.clock.Now()
+	c.scheduleNext(e, scheduled, now)
+
+	if e.running > 0 {
+		switch e.overlap {
+		case OverlapSkip:
+			e.record(Run{Scheduled: scheduled, Started: now, Finished: now, Status: RunSkipped})
+			e.mu.Unlock()
+			return
+		case OverlapQueue:
+			e.queue = append(e.queue, scheduled)
+			e.mu.Unlock()
+			return
+		}
+	}
+	e.running++
+	e.mu.Unlock()
+
+	for {
+		c.run(e, scheduled)
+
+		e.mu.Lock()
+		if len(e.queue) == 0 {
+			e.running--
+			e.mu.Unlock()
+			return
+		}
+		scheduled = e.queue[0]
+		e.queue = e.queue[1:]
+		e.mu.Unlock()
+	}
+}
+
+func (c *Cron) run(e *entry, scheduled time.Time) {
+	r := Run{Scheduled: scheduled, Started: c.clock.Now()}
+	r.Err = call(c.ctx, e.job)
+	r.Finished = c.clock.Now()
+	if r.Err != nil {
+		r.Status = RunFailed
+	}
+
+	e.mu.Lock()
+	e.record(r)
+	e.mu.Unlock()
+}
+
+// call 执行任务，把 panic 转换成带调用栈的 *panics.Error
+func call(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panics.New(v)
+		}
+	}()
+	return job(ctx)
+}
+
+// record 记录一次执行，调用时需要持有 e.mu
+func (e *entry) record(r Run) {
+	if e.historySize <= 0 {
+		return
+	}
+	if len(e.history) == e.historySize {
+		copy(e.history, e.history[1:])
+		e.history = e.history[:len(e.history)-1]
+	}
+	e.history = append(e.history, r)
+}
+
+func (e *entry) snapshot() Entry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return Entry{
+		ID:      e.id,
+		Name:    e.name,
+		Spec:    e.spec,
+		Next:    e.next,
+		Running: e.running,
+		Queued:  len(e.queue),
+		Missed:  e.missed,
+		History: append([]Run(nil), e.history...),
+	}
+}
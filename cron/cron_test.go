@@ -0,0 +1,254 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"concurrence/clock"
+	"concurrence/internal/panics"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestCron(opts ...Option) (*Cron, *clock.Fake) {
+	c := clock.NewFake(epoch)
+	return New(append([]Option{WithClock(c), WithLocation(time.UTC), WithWorkers(4)}, opts...)...), c
+}
+
+// advance 等 scheduler 开始等待下一个任务后再拨动时间
+func advance(c *clock.Fake, d time.Duration) {
+	c.BlockUntil(1)
+	c.Advance(d)
+}
+
+func history(cr *Cron, id EntryID) []Run {
+	e, _ := cr.Entry(id)
+	return e.History
+}
+
+func TestCron(t *testing.T) {
+	cr, c := newTestCron()
+	defer cr.Stop()
+
+	errFail := errors.New("fail")
+	var n atomic.Int32
+	id, err := cr.Add("*/10 * * * * *", func(ctx context.Context) error {
+		if n.Add(1) == 2 {
+			return errFail
+		}
+		return nil
+	}, WithName("ten seconds"), WithHistory(2))
+	assert.NoError(t, err)
+
+	e, ok := cr.Entry(id)
+	assert.True(t, ok)
+	assert.Equal(t, "ten seconds", e.Name)
+	assert.Equal(t, "*/10 * * * * *", e.Spec)
+	assert.Equal(t, epoch.Add(10*time.Second), e.Next)
+
+	for i := range 3 {
+		advance(c, 10*time.Second)
+		assert.Eventually(t, func() bool { return n.Load() == int32(i+1) }, time.Second, time.Millisecond)
+	}
+
+	// 只保留最近 2 次
+	assert.Eventually(t, func() bool {
+		h := history(cr, id)
+		return len(h) == 2 && h[1].Scheduled.Equal(epoch.Add(30*time.Second))
+	}, time.Second, time.Millisecond)
+	h := history(cr, id)
+	assert.Equal(t, RunFailed, h[0].Status)
+	assert.ErrorIs(t, h[0].Err, errFail)
+	assert.Equal(t, epoch.Add(20*time.Second), h[0].Scheduled)
+	assert.Equal(t, RunSucceeded, h[1].Status)
+
+	_, err = cr.Add("* * *", nil)
+	assert.ErrorIs(t, err, ErrInvalidSpec)
+}
+
+func TestCronOverlap(t *testing.T) {
+	tests := []struct {
+		policy  OverlapPolicy
+		runs    int32 // 第一次执行被阻塞期间又触发两次，最终执行的次数
+		skipped int
+	}{
+		{OverlapAllow, 3, 0},
+		{OverlapSkip, 1, 2},
+		{OverlapQueue, 3, 0},
+	}
+	for _, tt := range tests {
+		cr, c := newTestCron()
+
+		release := make(chan struct{})
+		var started, finished atomic.Int32
+		id, err := cr.Add("* * * * * *", func(ctx context.Context) error {
+			if started.Add(1) == 1 {
+				<-release
+			}
+			finished.Add(1)
+			return nil
+		}, WithOverlap(tt.policy))
+		assert.NoError(t, err)
+
+		e := cr.entries[id]
+		skipped := func() int {
+			n := 0
+			for _, r := range e.snapshot().History {
+				if r.Status == RunSkipped {
+					n++
+				}
+			}
+			return n
+		}
+
+		advance(c, time.Second)
+		assert.Eventually(t, func() bool { return started.Load() == 1 }, time.Second, time.Millisecond)
+		for range 2 {
+			advance(c, time.Second)
+		}
+
+		// 等两次触发都处理完
+		switch tt.policy {
+		case OverlapAllow:
+			// 同时执行，不等第一次
+			assert.Eventually(t, func() bool { return finished.Load() == 2 }, time.Second, time.Millisecond)
+		case OverlapSkip:
+			assert.Eventually(t, func() bool { return skipped() == 2 }, time.Second, time.Millisecond)
+		case OverlapQueue:
+			assert.Eventually(t, func() bool { return e.snapshot().Queued == 2 }, time.Second, time.Millisecond)
+			assert.Equal(t, int32(0), finished.Load())
+		}
+
+		close(release)
+		assert.Eventually(t, func() bool { return finished.Load() == tt.runs }, time.Second, time.Millisecond, "policy %d", tt.policy)
+		cr.Remove(id)
+		assert.NoError(t, cr.sched.Wait())
+		assert.Equal(t, tt.runs, finished.Load(), "policy %d", tt.policy)
+		assert.Equal(t, tt.skipped, skipped(), "policy %d", tt.policy)
+		cr.Stop()
+	}
+}
+
+func TestCronCatchUp(t *testing.T) {
+	tests := []struct {
+		policy CatchUpPolicy
+		runs   int32
+		missed int
+	}{
+		// 一次拨过 10 分钟，错过了 2~10 分钟的 9 次
+		{CatchUpNone, 1, 9},
+		{CatchUpOnce, 2, 8},
+		{CatchUpAll, 10, 0},
+	}
+	for _, tt := range tests {
+		cr, c := newTestCron()
+
+		var runs atomic.Int32
+		id, err := cr.Add("* * * * *", func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}, WithCatchUp(tt.policy), WithHistory(100))
+		assert.NoError(t, err)
+
+		advance(c, 10*time.Minute)
+		// 追上之后下一次是第 11 分钟
+		assert.Eventually(t, func() bool {
+			e, _ := cr.Entry(id)
+			return e.Next.Equal(epoch.Add(11*time.Minute)) && e.Running == 0 && len(e.History) == int(tt.runs)
+		}, time.Second, time.Millisecond, "policy %d", tt.policy)
+
+		e, _ := cr.Entry(id)
+		assert.Equal(t, tt.runs, runs.Load(), "policy %d", tt.policy)
+		assert.Equal(t, tt.missed, e.Missed, "policy %d", tt.policy)
+		assert.Equal(t, epoch.Add(11*time.Minute), e.Next, "policy %d", tt.policy)
+		cr.Stop()
+	}
+}
+
+func TestCronJitter(t *testing.T) {
+	cr, c := newTestCron()
+	defer cr.Stop()
+
+	id, err := cr.Add("@every 1m", func(ctx context.Context) error { return nil }, WithJitter(30*time.Second))
+	assert.NoError(t, err)
+
+	// 实际的触发时间在 [Next, Next+30s) 之间
+	for range 20 {
+		e := cr.entries[id]
+		e.mu.Lock()
+		next, at := e.next, e.task.When()
+		e.mu.Unlock()
+		assert.False(t, at.Before(next))
+		assert.Less(t, at.Sub(next), 30*time.Second)
+
+		c.BlockUntil(1)
+		c.Set(at)
+		assert.Eventually(t, func() bool {
+			e, _ := cr.Entry(id)
+			return e.Next.After(next)
+		}, time.Second, time.Millisecond)
+	}
+}
+
+func TestCronRemoveAndStop(t *testing.T) {
+	cr, c := newTestCron()
+
+	var removed atomic.Int32
+	id1, err := cr.Add("@every 1s", func(ctx context.Context) error {
+		removed.Add(1)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	started := make(chan struct{})
+	stopped := make(chan error, 1)
+	id2, err := cr.Add("@every 2s", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ctx.Err()
+	})
+	assert.NoError(t, err)
+
+	entries := cr.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, id1, entries[0].ID)
+	assert.Equal(t, id2, entries[1].ID)
+
+	assert.True(t, cr.Remove(id1))
+	assert.False(t, cr.Remove(id1))
+	_, ok := cr.Entry(id1)
+	assert.False(t, ok)
+
+	advance(c, 2*time.Second)
+	<-started
+	assert.Equal(t, int32(0), removed.Load())
+
+	// Stop 取消正在执行的任务的 ctx 并等待它返回
+	cr.Stop()
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+	e, _ := cr.Entry(id2)
+	assert.Equal(t, 0, e.Running)
+	assert.Equal(t, RunFailed, e.History[0].Status)
+}
+
+func TestCronPanic(t *testing.T) {
+	cr, c := newTestCron()
+	defer cr.Stop()
+
+	id, err := cr.Add("@every 1s", func(ctx context.Context) error { panic("boom") })
+	assert.NoError(t, err)
+	advance(c, time.Second)
+	assert.Eventually(t, func() bool { return len(history(cr, id)) == 1 }, time.Second, time.Millisecond)
+	err = history(cr, id)[0].Err
+	assert.ErrorContains(t, err, "boom")
+	// 错误里带着 panic 的调用栈
+	var pe *panics.Error
+	assert.ErrorAs(t, err, &pe)
+	assert.Contains(t, string(pe.Stack), "cron_test.go")
+}
@@ -0,0 +1,299 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持的表达式：
+//   - 5 个字段：分 时 日 月 周
+//   - 6 个字段：秒 分 时 日 月 周
+//   - 每个字段可以是 *、?、数字、范围 a-b、步长 */n a-b/n a/n，用逗号分隔多个；月和周可以用英文缩写 JAN、MON，周日可以写 0 或 7
+//   - 日和周都不是 * 时，和标准 cron 一样满足任意一个就执行
+//   - @yearly(@annually)、@monthly、@weekly、@daily(@midnight)、@hourly、@every <duration>
+//   - 开头加 CRON_TZ=Asia/Shanghai 或 TZ=Asia/Shanghai 指定时区
+
+// Schedule 计算下一次执行的时间
+type Schedule interface {
+	// Next 返回 t 之后的下一次执行时间，没有下一次时返回零值
+	Next(t time.Time) time.Time
+}
+
+// ErrInvalidSpec 表达式格式错误
+var ErrInvalidSpec = errors.New("cron: invalid spec")
+
+type bounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 周日可以写 7，解析后合并到 0
+	dow = bounds{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse 解析表达式，没有指定时区时使用 time.Local
+func Parse(spec string) (Schedule, error) {
+	return ParseInLocation(spec, time.Local)
+}
+
+// ParseInLocation 解析表达式，没有指定时区时使用 loc
+func ParseInLocation(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if !strings.HasPrefix(spec, prefix) {
+			continue
+		}
+		name, rest, _ := strings.Cut(spec[len(prefix):], " ")
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: time zone %q: %v", ErrInvalidSpec, name, err)
+		}
+		loc, spec = l, strings.TrimSpace(rest)
+		break
+	}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSpec, spec)
+		}
+		return every(d), nil
+	}
+	if s, ok := descriptors[spec]; ok {
+		spec = s
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("%w: unknown descriptor %q", ErrInvalidSpec, spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, found %d: %q", ErrInvalidSpec, len(fields), spec)
+	}
+
+	s := &specSchedule{loc: loc}
+	var err error
+	for i, f := range []struct {
+		bits *uint64
+		b    bounds
+	}{
+		{&s.second, seconds}, {&s.minute, minutes}, {&s.hour, hours},
+		{&s.dom, dom}, {&s.month, months}, {&s.dow, dow},
+	} {
+		if *f.bits, err = parseField(fields[i], f.b); err != nil {
+			return nil, err
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar = isStar(fields[3])
+	s.dowStar = isStar(fields[5])
+	return s, nil
+}
+
+func isStar(field string) bool {
+	return field == "*" || field == "?" || strings.HasPrefix(field, "*/")
+}
+
+// parseField 把一个字段解析成位图，第 n 位为 1 表示 n 满足
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		r, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= r
+	}
+	return bits, nil
+}
+
+func parseRange(expr string, b bounds) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+	lowExpr, highExpr, hasHigh := strings.Cut(rangeExpr, "-")
+
+	var start, end int
+	var err error
+	if lowExpr == "*" || lowExpr == "?" {
+		if hasHigh {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidSpec, expr)
+		}
+		start, end = b.min, b.max
+	} else {
+		if start, err = parseValue(lowExpr, b); err != nil {
+			return 0, err
+		}
+		end = start
+		if hasHigh {
+			if end, err = parseValue(highExpr, b); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			// a/n 表示从 a 开始到最大值
+			end = b.max
+		}
+	}
+
+	step := 1
+	if hasStep {
+		if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+			return 0, fmt.Errorf("%w: step of %q", ErrInvalidSpec, expr)
+		}
+	}
+	if start < b.min || end > b.max || start > end {
+		return 0, fmt.Errorf("%w: %q out of range [%d, %d]", ErrInvalidSpec, expr, b.min, b.max)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+func parseValue(expr string, b bounds) (int, error) {
+	if v, ok := b.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSpec, expr)
+	}
+	return v, nil
+}
+
+// every 固定间隔执行，对应 @every
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	if d >= time.Second {
+		// 对齐到秒，和 cron 表达式一样
+		t = t.Truncate(time.Second)
+	}
+	return t.Add(d)
+}
+
+// specSchedule cron 表达式，每个字段是一个位图
+type specSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	domStar, dowStar                      bool
+	loc                                   *time.Location
+}
+
+func (s *specSchedule) Next(t time.Time) time.Time {
+	// 从下一个整秒开始，按 月 -> 日 -> 时 -> 分 -> 秒 的顺序找到第一个满足的时间
+	// 高位的字段变化时，低位的字段重置为最小值；跨年之后从头检查
+	orig := t.Location()
+	t = t.In(s.loc)
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	reset := false // 低位的字段是否已经重置
+	limit := t.Year() + 5
+
+wrap:
+	for t.Year() <= limit {
+		for s.month&(1<<uint(t.Month())) == 0 {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, s.loc)
+			}
+			t = t.AddDate(0, 1, 0)
+			if t.Month() == time.January {
+				continue wrap
+			}
+		}
+
+		for !s.dayMatches(t) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.loc)
+			}
+			t = t.AddDate(0, 0, 1)
+			// 夏令时切换的那天 0 点可能不存在，AddDate 之后不一定是 0 点
+			if h := t.Hour(); h != 0 {
+				if h > 12 {
+					t = t.Add(time.Duration(24-h) * time.Hour)
+				} else {
+					t = t.Add(-time.Duration(h) * time.Hour)
+				}
+			}
+			if t.Day() == 1 {
+				continue wrap
+			}
+		}
+
+		for s.hour&(1<<uint(t.Hour())) == 0 {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, s.loc)
+			}
+			t = t.Add(time.Hour)
+			if t.Hour() == 0 {
+				continue wrap
+			}
+		}
+
+		for s.minute&(1<<uint(t.Minute())) == 0 {
+			if !reset {
+				reset = true
+				t = t.Truncate(time.Minute)
+			}
+			t = t.Add(time.Minute)
+			if t.Minute() == 0 {
+				continue wrap
+			}
+		}
+
+		for s.second&(1<<uint(t.Second())) == 0 {
+			if !reset {
+				reset = true
+				t = t.Truncate(time.Second)
+			}
+			t = t.Add(time.Second)
+			if t.Second() == 0 {
+				continue wrap
+			}
+		}
+
+		return t.In(orig)
+	}
+	// 5 年内都没有满足的时间，比如 2 月 30 日
+	return time.Time{}
+}
+
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,124 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustTime(t *testing.T, loc *time.Location, s string) time.Time {
+	t.Helper()
+	tm, err := time.ParseInLocation("2006-01-02 15:04:05 Mon", s, loc)
+	assert.NoError(t, err)
+	return tm
+}
+
+func TestParseNext(t *testing.T) {
+	tests := []struct {
+		spec, from, want string
+	}{
+		// 5 个字段，秒为 0
+		{"* * * * *", "2024-01-01 00:00:00 Mon", "2024-01-01 00:01:00 Mon"},
+		{"30 9 * * *", "2024-01-01 10:00:00 Mon", "2024-01-02 09:30:00 Tue"},
+		{"*/15 * * * *", "2024-01-01 00:16:00 Mon", "2024-01-01 00:30:00 Mon"},
+		{"0 9-17/4 * * *", "2024-01-01 10:00:00 Mon", "2024-01-01 13:00:00 Mon"},
+		{"0 0 1,15 * *", "2024-01-02 00:00:00 Tue", "2024-01-15 00:00:00 Mon"},
+		{"0 0 * * MON-FRI", "2024-01-05 12:00:00 Fri", "2024-01-08 00:00:00 Mon"},
+		{"0 0 * * 7", "2024-01-01 00:00:00 Mon", "2024-01-07 00:00:00 Sun"},
+		{"0 0 1 feb *", "2024-03-01 00:00:00 Fri", "2025-02-01 00:00:00 Sat"},
+		{"0 0 29 2 *", "2024-03-01 00:00:00 Fri", "2028-02-29 00:00:00 Tue"},
+		{"0 0 31 * *", "2024-04-01 00:00:00 Mon", "2024-05-31 00:00:00 Fri"},
+		// 日和周都指定时满足任意一个
+		{"0 0 13 * FRI", "2024-09-01 00:00:00 Sun", "2024-09-06 00:00:00 Fri"},
+		{"0 0 13 * FRI", "2024-09-07 00:00:00 Sat", "2024-09-13 00:00:00 Fri"},
+		{"0 0 ? * 1", "2024-01-02 00:00:00 Tue", "2024-01-08 00:00:00 Mon"},
+		// 6 个字段
+		{"*/10 * * * * *", "2024-01-01 00:00:05 Mon", "2024-01-01 00:00:10 Mon"},
+		{"5/20 0 0 * * *", "2024-01-01 00:00:30 Mon", "2024-01-01 00:00:45 Mon"},
+		{"0 59 23 31 12 *", "2024-01-01 00:00:00 Mon", "2024-12-31 23:59:00 Tue"},
+		// 描述符
+		{"@yearly", "2024-06-01 00:00:00 Sat", "2025-01-01 00:00:00 Wed"},
+		{"@monthly", "2024-06-01 00:00:00 Sat", "2024-07-01 00:00:00 Mon"},
+		{"@weekly", "2024-06-01 00:00:00 Sat", "2024-06-02 00:00:00 Sun"},
+		{"@daily", "2024-06-01 12:00:00 Sat", "2024-06-02 00:00:00 Sun"},
+		{"@hourly", "2024-06-01 12:00:00 Sat", "2024-06-01 13:00:00 Sat"},
+		{"@every 90m", "2024-06-01 12:00:00 Sat", "2024-06-01 13:30:00 Sat"},
+	}
+	for _, tt := range tests {
+		s, err := ParseInLocation(tt.spec, time.UTC)
+		if !assert.NoError(t, err, tt.spec) {
+			continue
+		}
+		got := s.Next(mustTime(t, time.UTC, tt.from))
+		assert.Equal(t, mustTime(t, time.UTC, tt.want), got, "%s from %s", tt.spec, tt.from)
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	// 表达式里的时区优先，返回的时间和参数的时区一样
+	s, err := ParseInLocation("CRON_TZ=Asia/Shanghai 0 9 * * *", time.UTC)
+	assert.NoError(t, err)
+	got := s.Next(mustTime(t, time.UTC, "2024-01-01 00:00:00 Mon"))
+	assert.Equal(t, mustTime(t, shanghai, "2024-01-01 09:00:00 Mon").In(time.UTC), got)
+	assert.Equal(t, time.UTC, got.Location())
+
+	s, err = ParseInLocation("TZ=Asia/Shanghai @daily", time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, mustTime(t, time.UTC, "2024-01-01 16:00:00 Mon"), s.Next(mustTime(t, time.UTC, "2024-01-01 00:00:00 Mon")))
+
+	_, err = Parse("CRON_TZ=Nowhere/City * * * * *")
+	assert.ErrorIs(t, err, ErrInvalidSpec)
+}
+
+func TestParseDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	// 2024-03-10 02:00 跳到 03:00，2:30 不存在，那一天跳过
+	s, err := ParseInLocation("30 2 * * *", ny)
+	assert.NoError(t, err)
+	got := s.Next(time.Date(2024, 3, 9, 12, 0, 0, 0, ny))
+	assert.Equal(t, time.Date(2024, 3, 11, 2, 30, 0, 0, ny), got)
+
+	// 每小时执行的任务在切换时不会卡住
+	s, err = ParseInLocation("0 * * * *", ny)
+	assert.NoError(t, err)
+	got = s.Next(time.Date(2024, 3, 10, 1, 30, 0, 0, ny))
+	assert.Equal(t, time.Date(2024, 3, 10, 3, 0, 0, 0, ny), got)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*-5 * * * *",
+		"a * * * *",
+		"@reboot",
+		"@every",
+		"@every -1s",
+	} {
+		_, err := Parse(spec)
+		assert.ErrorIs(t, err, ErrInvalidSpec, spec)
+	}
+
+	// 不存在的日期返回零值
+	s, err := Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
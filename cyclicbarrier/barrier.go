@@ -0,0 +1,182 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CyclicBarrier 的语义参照 Java 的 java.util.concurrent.CyclicBarrier：
+// 每一轮（generation）有 parties 个参与者，最后一个到达的参与者执行 barrierAction，然后唤醒所有人进入下一轮
+// 一轮中只要有一个参与者的 ctx 被取消，或者 barrierAction 返回错误，这一轮就被打破（broken），
+// 其他参与者得到 ErrBrokenBarrier，之后的 Await 也立即返回 ErrBrokenBarrier，直到调用 Reset
+
+// ErrBrokenBarrier 屏障已经被打破
+var ErrBrokenBarrier = errors.New("cyclicbarrier: broken barrier")
+
+// errReset 屏障被 Reset 打破
+var errReset = errors.New("cyclicbarrier: barrier reset")
+
+// BrokenBarrierError 是屏障被打破时其他参与者得到的错误，Cause 是打破屏障的原因
+// errors.Is(err, ErrBrokenBarrier) 为 true
+type BrokenBarrierError struct {
+	Cause error
+}
+
+func (e *BrokenBarrierError) Error() string {
+	return ErrBrokenBarrier.Error() + ": " + e.Cause.Error()
+}
+
+// Is 让 errors.Is(err, ErrBrokenBarrier) 成立
+func (e *BrokenBarrierError) Is(target error) bool {
+	return target == ErrBrokenBarrier
+}
+
+// Unwrap 返回打破屏障的原因
+func (e *BrokenBarrierError) Unwrap() error {
+	return e.Cause
+}
+
+// CyclicBarrier 循环屏障
+type CyclicBarrier struct {
+	parties int
+	action  func() error
+
+	mu  sync.Mutex
+	gen *generation
+}
+
+// generation 是屏障的一轮
+type generation struct {
+	done   chan struct{} // 这一轮结束（全部到达或者被打破）时关闭
+	count  int           // 已经到达的参与者数量
+	broken error         // 打破这一轮的原因，为 nil 表示没有被打破
+}
+
+// New 创建一个有 parties 个参与者的循环屏障
+func New(parties int) *CyclicBarrier {
+	return NewWithAction(parties, nil)
+}
+
+// NewWithAction 创建一个有 parties 个参与者的循环屏障，每一轮最后一个到达的参与者执行 action 后再唤醒其他人
+func NewWithAction(parties int, action func() error) *CyclicBarrier {
+	if parties <= 0 {
+		panic("cyclicbarrier: parties must be positive")
+	}
+	return &CyclicBarrier{
+		parties: parties,
+		action:  action,
+		gen:     &generation{done: make(chan struct{})},
+	}
+}
+
+// Await 到达屏障，阻塞直到这一轮的参与者全部到达
+// 返回到达的序号：第一个到达的是 parties-1，最后一个到达的是 0
+// ctx 被取消时返回 ctx.Err() 并打破屏障；最后一个到达者执行的 action 返回错误或者 panic 时，它得到这个错误并打破屏障；
+// 其他参与者得到 *BrokenBarrierError
+func (b *CyclicBarrier) Await(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	g := b.gen
+	if g.broken != nil {
+		b.mu.Unlock()
+		return 0, &BrokenBarrierError{Cause: g.broken}
+	}
+	if err := ctx.Err(); err != nil {
+		b.breakBarrier(g, err)
+		b.mu.Unlock()
+		return 0, err
+	}
+
+	g.count++
+	index := b.parties - g.count
+	if index == 0 {
+		// 最后一个到达，执行 action 之后进入下一轮
+		err := b.runAction(g)
+		b.mu.Unlock()
+		return 0, err
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-g.done:
+	case <-ctx.Done():
+		b.mu.Lock()
+		if b.gen == g && g.broken == nil {
+			// 这一轮还没结束，自己退出就打破了屏障
+			b.breakBarrier(g, ctx.Err())
+			b.mu.Unlock()
+			return index, ctx.Err()
+		}
+		b.mu.Unlock()
+	}
+
+	// 这一轮已经结束，可能和取消同时发生，以结束的结果为准
+	if g.broken != nil {
+		return index, &BrokenBarrierError{Cause: g.broken}
+	}
+	return index, nil
+}
+
+// Reset 把屏障重置为初始状态，正在等待的参与者得到 *BrokenBarrierError
+func (b *CyclicBarrier) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g := b.gen; g.broken == nil && g.count > 0 {
+		b.breakBarrier(g, errReset)
+	}
+	b.gen = &generation{done: make(chan struct{})}
+}
+
+// IsBroken 屏障是否被打破
+func (b *CyclicBarrier) IsBroken() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gen.broken != nil
+}
+
+// GetParties 返回参与者数量
+func (b *CyclicBarrier) GetParties() int {
+	return b.parties
+}
+
+// GetNumberWaiting 返回这一轮正在等待的参与者数量
+func (b *CyclicBarrier) GetNumberWaiting() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.gen.broken != nil {
+		return 0
+	}
+	return b.gen.count
+}
+
+// runAction 执行 action，成功进入下一轮，失败或者 panic 打破屏障，调用时需要持有 b.mu
+func (b *CyclicBarrier) runAction(g *generation) error {
+	if b.action != nil {
+		if err := callAction(b.action); err != nil {
+			b.breakBarrier(g, err)
+			return err
+		}
+	}
+
+	close(g.done)
+	b.gen = &generation{done: make(chan struct{})}
+	return nil
+}
+
+// callAction 执行 action，把 panic 转换成错误，避免 panic 时 b.mu 没有释放
+func callAction(action func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("cyclicbarrier: barrier action panicked: %v", v)
+		}
+	}()
+	return action()
+}
+
+// breakBarrier 打破这一轮，唤醒所有等待者，调用时需要持有 b.mu
+func (b *CyclicBarrier) breakBarrier(g *generation, cause error) {
+	g.broken = cause
+	close(g.done)
+}
@@ -0,0 +1,146 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCyclicBarrier(t *testing.T) {
+	const parties, rounds = 5, 10
+	var actions atomic.Int32
+	b := NewWithAction(parties, func() error {
+		actions.Add(1)
+		return nil
+	})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		indexes = make([][]int, rounds)
+	)
+	for range parties {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range rounds {
+				idx, err := b.Await(context.Background())
+				assert.NoError(t, err)
+				mu.Lock()
+				indexes[r] = append(indexes[r], idx)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每一轮执行一次 action，到达序号是 0 ~ parties-1
+	assert.Equal(t, int32(rounds), actions.Load())
+	for _, idx := range indexes {
+		sort.Ints(idx)
+		assert.Equal(t, []int{0, 1, 2, 3, 4}, idx)
+	}
+	assert.False(t, b.IsBroken())
+	assert.Equal(t, 0, b.GetNumberWaiting())
+}
+
+func TestCyclicBarrierCancel(t *testing.T) {
+	b := New(3)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return b.GetNumberWaiting() == 1 }, time.Second, time.Millisecond)
+
+	// 一个参与者被取消，屏障被打破，其他参与者得到 ErrBrokenBarrier
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := b.Await(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = <-errs
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+	var bbe *BrokenBarrierError
+	assert.ErrorAs(t, err, &bbe)
+	assert.ErrorIs(t, bbe.Cause, context.DeadlineExceeded)
+
+	// 打破之后的 Await 立即返回，直到 Reset
+	assert.True(t, b.IsBroken())
+	_, err = b.Await(context.Background())
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+
+	b.Reset()
+	assert.False(t, b.IsBroken())
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := b.Await(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCyclicBarrierActionError(t *testing.T) {
+	errAction := errors.New("action failed")
+	b := NewWithAction(2, func() error { return errAction })
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return b.GetNumberWaiting() == 1 }, time.Second, time.Millisecond)
+
+	// 执行 action 的参与者得到 action 的错误，其他人得到 ErrBrokenBarrier
+	_, err := b.Await(context.Background())
+	assert.ErrorIs(t, err, errAction)
+	err = <-errs
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+	assert.ErrorIs(t, err, errAction)
+}
+
+func TestCyclicBarrierActionPanic(t *testing.T) {
+	b := NewWithAction(2, func() error { panic("boom") })
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return b.GetNumberWaiting() == 1 }, time.Second, time.Millisecond)
+
+	// action panic 时和返回错误一样打破屏障，锁已经释放
+	_, err := b.Await(context.Background())
+	assert.ErrorContains(t, err, "boom")
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	assert.True(t, b.IsBroken())
+	b.Reset()
+	assert.False(t, b.IsBroken())
+}
+
+func TestCyclicBarrierReset(t *testing.T) {
+	b := New(2)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Await(context.Background())
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return b.GetNumberWaiting() == 1 }, time.Second, time.Millisecond)
+
+	// 正在等待的参与者被 Reset 唤醒
+	b.Reset()
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	assert.False(t, b.IsBroken())
+	assert.Equal(t, 2, b.GetParties())
+}
@@ -3,7 +3,6 @@ package cyclicbarrier
 import (
 	"context"
 	"fmt"
-	"github.com/marusama/cyclicbarrier"
 	"log"
 	"math/rand/v2"
 	"sync"
@@ -29,9 +28,11 @@ import (
 // WaitGroup 适合用在一个 goroutine 等待一组 goroutine 到达同一个检查点
 // CyclicBarrier 的参与者互相等待，WaitGroup一般是父 goroutine 等待子 goroutine 完成，子 goroutine 之间不需要相互等待
 
+// Demo 原来使用的是 github.com/marusama/cyclicbarrier，现在换成了本包的 CyclicBarrier，见 barrier.go
+
 func Demo() {
 	cnt := 0
-	b := cyclicbarrier.NewWithAction(10, func() error {
+	b := NewWithAction(10, func() error {
 		cnt++
 		return nil
 	})
@@ -47,7 +48,7 @@ func Demo() {
 				time.Sleep(time.Duration(rand.IntN(10)) * time.Second)
 				// 每一轮随机休眠一段时间，再来到屏障处
 				log.Printf("goroutine %d, 来到第 %d 轮屏障, wait\n", i, j)
-				err := b.Await(context.TODO())
+				_, err := b.Await(context.TODO())
 				log.Printf("goroutine %d, 冲破第 %d 轮屏障\n", i, j)
 				if err != nil {
 					panic(err)
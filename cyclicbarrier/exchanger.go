@@ -0,0 +1,120 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// Exchanger 参照 Java 的 java.util.concurrent.Exchanger，两个 goroutine 在交换点相遇并交换数据，常用于双缓冲
+// 只有一个交换点时，并发的 Exchange 都竞争同一个位置；这里用消除数组（elimination array）分散竞争：
+// 交换点有多个槽位，先来的在某个槽位上等待，后来的在同一个槽位上取走它的数据并留下自己的
+// 0 号槽位一直等待；其他槽位只自旋一小会儿，没人来就退回去，保证只有两个 goroutine 时也能相遇
+// 在槽位上发生冲突时扩大使用的槽位范围，等不到对方时缩小范围
+
+const (
+	cacheLine = 64
+	// spins 非 0 号槽位上等待的自旋次数
+	spins = 1 << 6
+)
+
+// Exchanger 交换器
+type Exchanger[T any] struct {
+	bound atomic.Int32 // 使用的槽位范围 [0, bound]
+	arena []slot[T]
+}
+
+// slot 交换槽位，填充到一个缓存行，避免伪共享
+type slot[T any] struct {
+	p atomic.Pointer[exchangeNode[T]]
+	_ [cacheLine - 8]byte
+}
+
+// exchangeNode 在槽位上等待的 goroutine
+type exchangeNode[T any] struct {
+	item T
+	hole chan T // 对方的数据，容量为 1
+}
+
+// NewExchanger 创建一个交换器，槽位数量和 GOMAXPROCS 有关
+func NewExchanger[T any]() *Exchanger[T] {
+	n := min(runtime.GOMAXPROCS(0)/2, 32) + 1
+	return &Exchanger[T]{arena: make([]slot[T], n)}
+}
+
+// Exchange 等待另一个 goroutine 到达交换点，把 v 交给它并返回它的数据
+// ctx 被取消时返回 ctx.Err()；取消的同时完成了交换，以交换的结果为准
+func (e *Exchanger[T]) Exchange(ctx context.Context, v T) (T, error) {
+	var zero T
+	me := &exchangeNode[T]{item: v, hole: make(chan T, 1)}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+
+		bound := int(e.bound.Load())
+		i := 0
+		if bound > 0 {
+			i = rand.IntN(bound + 1)
+		}
+		s := &e.arena[i]
+
+		if other := s.p.Load(); other != nil {
+			if s.p.CompareAndSwap(other, nil) {
+				other.hole <- v
+				return other.item, nil
+			}
+			// 被别人抢先了，扩大范围
+			e.grow(bound)
+			continue
+		}
+		if !s.p.CompareAndSwap(nil, me) {
+			e.grow(bound)
+			continue
+		}
+
+		if i > 0 {
+			if item, ok := e.spin(s, me); ok {
+				return item, nil
+			}
+			// 等不到对方，缩小范围回到前面的槽位
+			e.bound.CompareAndSwap(int32(bound), int32(bound-1))
+			continue
+		}
+
+		select {
+		case item := <-me.hole:
+			return item, nil
+		case <-ctx.Done():
+			if s.p.CompareAndSwap(me, nil) {
+				return zero, ctx.Err()
+			}
+			// 已经被取走了，对方一定会留下数据
+			return <-me.hole, nil
+		}
+	}
+}
+
+// spin 在非 0 号槽位上自旋等待，超时后撤回，ok 为 false 表示没有完成交换
+func (e *Exchanger[T]) spin(s *slot[T], me *exchangeNode[T]) (item T, ok bool) {
+	for range spins {
+		select {
+		case item = <-me.hole:
+			return item, true
+		default:
+			runtime.Gosched()
+		}
+	}
+	if s.p.CompareAndSwap(me, nil) {
+		return item, false
+	}
+	return <-me.hole, true
+}
+
+func (e *Exchanger[T]) grow(bound int) {
+	if bound+1 < len(e.arena) {
+		e.bound.CompareAndSwap(int32(bound), int32(bound+1))
+	}
+}
@@ -0,0 +1,108 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExchanger(t *testing.T) {
+	e := NewExchanger[string]()
+
+	got := make(chan string, 1)
+	go func() {
+		v, err := e.Exchange(context.Background(), "ping")
+		assert.NoError(t, err)
+		got <- v
+	}()
+
+	v, err := e.Exchange(context.Background(), "pong")
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", v)
+	assert.Equal(t, "pong", <-got)
+}
+
+func TestExchangerDoubleBuffer(t *testing.T) {
+	// 生产者填满缓冲区后和消费者交换空缓冲区
+	e := NewExchanger[[]int]()
+	const rounds, size = 100, 10
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		buf := make([]int, 0, size)
+		for r := range rounds {
+			for i := range size {
+				buf = append(buf, r*size+i)
+			}
+			var err error
+			buf, err = e.Exchange(context.Background(), buf)
+			assert.NoError(t, err)
+			assert.Empty(t, buf)
+		}
+	}()
+
+	buf := make([]int, 0, size)
+	sum := 0
+	for range rounds {
+		var err error
+		buf, err = e.Exchange(context.Background(), buf)
+		assert.NoError(t, err)
+		assert.Len(t, buf, size)
+		for _, v := range buf {
+			sum += v
+		}
+		buf = buf[:0]
+	}
+	wg.Wait()
+	assert.Equal(t, rounds*size*(rounds*size-1)/2, sum)
+}
+
+func TestExchangerContention(t *testing.T) {
+	// 很多 goroutine 同时交换，每一对互相拿到对方的数据
+	e := NewExchanger[int]()
+	const n = 1000
+
+	got := make([]int, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := e.Exchange(context.Background(), i)
+			assert.NoError(t, err)
+			got[i] = v
+		}()
+	}
+	wg.Wait()
+
+	for i, v := range got {
+		assert.NotEqual(t, i, v)
+		assert.Equal(t, i, got[v])
+	}
+}
+
+func TestExchangerCancel(t *testing.T) {
+	e := NewExchanger[int]()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := e.Exchange(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 取消的一方离开了交换点，不会和之后的交换配对
+	got := make(chan int, 1)
+	go func() {
+		v, err := e.Exchange(context.Background(), 2)
+		assert.NoError(t, err)
+		got <- v
+	}()
+	v, err := e.Exchange(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, 3, <-got)
+}
@@ -0,0 +1,344 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Phaser 参照 Java 的 java.util.concurrent.Phaser，是参与者数量可以变化的循环屏障
+// CyclicBarrier 的参与者数量在创建时就固定了，Phaser 可以随时 Register 新的参与者，离开的参与者用 ArriveAndDeregister 注销
+// 每一轮叫一个阶段（phase），所有注册的参与者都到达后进入下一阶段，阶段号加 1
+// 进入下一阶段前调用 onAdvance，返回 true 时 Phaser 终止，默认在没有参与者时终止
+// onAdvance 执行时不持有锁，可以调用 Phaser 的方法，这时 Register 的参与者参与下一阶段
+//
+// 参与者很多时，所有人竞争同一个锁，可以把参与者分给多个子 Phaser（分层）：
+// 子 Phaser 的参与者全部到达后，子 Phaser 作为一个参与者到达父 Phaser，阶段号由根 Phaser 统一推进
+
+var (
+	// ErrTerminated Phaser 已经终止
+	ErrTerminated = errors.New("cyclicbarrier: phaser terminated")
+	// ErrUnregisteredArrive 到达的参与者比注册的多
+	ErrUnregisteredArrive = errors.New("cyclicbarrier: arrive of unregistered party")
+)
+
+// Phaser 阶段屏障
+type Phaser struct {
+	parent    *Phaser
+	root      *Phaser
+	onAdvance func(phase, registered int) bool // 只有根 Phaser 的有效
+
+	mu        sync.Mutex
+	phase     int           // 根 Phaser：当前阶段；子 Phaser：unarrived 对应的阶段，落后于根时需要同步
+	parties   int           // 注册的参与者数量
+	unarrived int           // 这一阶段还没有到达的参与者数量
+	terminate bool          // 只有根 Phaser 使用
+	advancing bool          // 只有根 Phaser 使用，正在执行 onAdvance
+	joining   chan struct{} // 只有子 Phaser 使用，正在注册到父 Phaser，结束时关闭
+	advance   chan struct{}
+}
+
+// NewPhaser 创建一个有 parties 个参与者的 Phaser，没有参与者时终止
+func NewPhaser(parties int) *Phaser {
+	return NewPhaserWithOnAdvance(parties, nil)
+}
+
+// NewPhaserWithOnAdvance 创建一个有 parties 个参与者的 Phaser
+// 每次进入下一阶段前调用 onAdvance，参数是刚结束的阶段号和注册的参与者数量，返回 true 时终止
+// onAdvance 为 nil 时在没有参与者时终止
+func NewPhaserWithOnAdvance(parties int, onAdvance func(phase, registered int) bool) *Phaser {
+	if parties < 0 {
+		panic("cyclicbarrier: negative parties")
+	}
+	if onAdvance == nil {
+		onAdvance = func(phase, registered int) bool { return registered == 0 }
+	}
+
+	p := &Phaser{
+		onAdvance: onAdvance,
+		parties:   parties,
+		unarrived: parties,
+		advance:   make(chan struct{}),
+	}
+	p.root = p
+	return p
+}
+
+// NewChildPhaser 创建一个子 Phaser，有参与者时作为一个参与者注册到 parent
+func NewChildPhaser(parent *Phaser, parties int) (*Phaser, error) {
+	if parties < 0 {
+		panic("cyclicbarrier: negative parties")
+	}
+
+	p := &Phaser{parent: parent, root: parent.root}
+	p.phase = p.root.Phase()
+	if parties > 0 {
+		if _, err := p.BulkRegister(parties); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Register 注册一个参与者，返回它参与的第一个阶段号
+func (p *Phaser) Register() (int, error) {
+	return p.BulkRegister(1)
+}
+
+// BulkRegister 注册 n 个参与者，返回它们参与的第一个阶段号
+// 子 Phaser 的参与者已经全部到达、正在等待根 Phaser 进入下一阶段时，会等到下一阶段再注册
+func (p *Phaser) BulkRegister(n int) (int, error) {
+	for {
+		p.mu.Lock()
+		if p.terminatedLocked() {
+			p.mu.Unlock()
+			return 0, ErrTerminated
+		}
+		p.reconcile()
+
+		if p.parent != nil && p.parties > 0 && p.unarrived == 0 {
+			// 这一阶段已经作为一个参与者到达了父 Phaser，新的参与者只能参与下一阶段
+			phase := p.phase
+			p.mu.Unlock()
+			if _, err := p.root.awaitAdvance(context.Background(), phase); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if p.parent != nil && p.parties == 0 && n > 0 {
+			// 第一次有参与者，作为一个参与者注册到父 Phaser
+			if p.joining != nil {
+				// 另一个调用者正在注册，等它完成
+				joining := p.joining
+				p.mu.Unlock()
+				<-joining
+				continue
+			}
+			return p.join(n)
+		}
+
+		if p.advancing {
+			// 根 Phaser 正在执行 onAdvance，新的参与者参与下一阶段，进入下一阶段时 unarrived 会重置
+			p.parties += n
+			phase := p.phase + 1
+			p.mu.Unlock()
+			return phase, nil
+		}
+
+		p.parties += n
+		p.unarrived += n
+		phase := p.phase
+		p.mu.Unlock()
+		return phase, nil
+	}
+}
+
+// join 子 Phaser 作为一个参与者注册到父 Phaser，再注册 n 个参与者
+// 调用时需要持有 p.mu，注册到父 Phaser 时释放，返回时已经释放
+func (p *Phaser) join(n int) (int, error) {
+	joining := make(chan struct{})
+	p.joining = joining
+	p.mu.Unlock()
+
+	phase, err := p.parent.Register()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.joining = nil
+	close(joining)
+	if err != nil {
+		return 0, err
+	}
+	p.phase = phase
+	p.parties += n
+	p.unarrived += n
+	return phase, nil
+}
+
+// Arrive 到达屏障但不等待其他参与者，返回到达的阶段号
+func (p *Phaser) Arrive() (int, error) {
+	return p.arrive(false)
+}
+
+// ArriveAndDeregister 到达屏障并注销，不再参与之后的阶段，返回到达的阶段号
+// Phaser 不区分是哪个参与者，参与者要离开时都用它注销，这一阶段不会再等它
+func (p *Phaser) ArriveAndDeregister() (int, error) {
+	return p.arrive(true)
+}
+
+// ArriveAndAwaitAdvance 到达屏障并等待其他参与者，返回进入的下一阶段号
+// ctx 被取消时返回 ctx.Err()，但是已经到达的不会撤销，和 Java 的语义一样
+func (p *Phaser) ArriveAndAwaitAdvance(ctx context.Context) (int, error) {
+	phase, err := p.arrive(false)
+	if err != nil {
+		return 0, err
+	}
+	return p.AwaitAdvance(ctx, phase)
+}
+
+// AwaitAdvance 等待 Phaser 从阶段 phase 进入下一阶段，返回下一阶段号；当前阶段已经过了 phase 时立即返回当前阶段号
+func (p *Phaser) AwaitAdvance(ctx context.Context, phase int) (int, error) {
+	return p.root.awaitAdvance(ctx, phase)
+}
+
+// Phase 返回当前阶段号
+func (p *Phaser) Phase() int {
+	r := p.root
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.phase
+}
+
+// IsTerminated Phaser 是否已经终止
+func (p *Phaser) IsTerminated() bool {
+	r := p.root
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.terminate
+}
+
+// RegisteredParties 返回注册的参与者数量，子 Phaser 算作父 Phaser 的一个参与者
+func (p *Phaser) RegisteredParties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parties
+}
+
+// UnarrivedParties 返回这一阶段还没有到达的参与者数量
+func (p *Phaser) UnarrivedParties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reconcile()
+	return p.unarrived
+}
+
+// Parent 返回父 Phaser，根 Phaser 返回 nil
+func (p *Phaser) Parent() *Phaser {
+	return p.parent
+}
+
+func (p *Phaser) arrive(deregister bool) (int, error) {
+	for {
+		p.mu.Lock()
+		if p.terminatedLocked() {
+			p.mu.Unlock()
+			return 0, ErrTerminated
+		}
+		p.reconcile()
+
+		if p.advancing {
+			// 根 Phaser 正在执行 onAdvance，这次到达属于下一阶段
+			phase := p.phase
+			p.mu.Unlock()
+			if _, err := p.awaitAdvance(context.Background(), phase); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if p.unarrived == 0 {
+			if p.parent == nil || p.parties == 0 {
+				p.mu.Unlock()
+				return 0, ErrUnregisteredArrive
+			}
+			// 子 Phaser 这一阶段已经全部到达，这次到达属于下一阶段
+			phase := p.phase
+			p.mu.Unlock()
+			if _, err := p.root.awaitAdvance(context.Background(), phase); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		phase := p.phase
+		p.unarrived--
+		if deregister {
+			p.parties--
+		}
+		last, parties := p.unarrived == 0, p.parties
+		if last && p.parent == nil {
+			p.advancing = true
+		}
+		// 调用父 Phaser 和 onAdvance 之前释放锁，免得它们回过头来调用这个 Phaser 时死锁
+		p.mu.Unlock()
+
+		if !last {
+			return phase, nil
+		}
+		if p.parent == nil {
+			p.advancePhase(phase, parties)
+			return phase, nil
+		}
+		// 没有参与者了就从父 Phaser 注销
+		if _, err := p.parent.arrive(parties == 0); err != nil {
+			return 0, err
+		}
+		return phase, nil
+	}
+}
+
+// advancePhase 根 Phaser 执行 onAdvance，然后进入下一阶段或者终止，调用时不能持有 p.mu
+func (p *Phaser) advancePhase(phase, registered int) {
+	terminate := true // onAdvance panic 时终止，免得等待的参与者永远阻塞
+	defer func() {
+		p.mu.Lock()
+		if terminate {
+			p.terminate = true
+		} else {
+			p.phase++
+			p.unarrived = p.parties
+		}
+		p.advancing = false
+		close(p.advance)
+		p.advance = make(chan struct{})
+		p.mu.Unlock()
+	}()
+
+	terminate = p.onAdvance(phase, registered)
+}
+
+// terminatedLocked 是否已经终止，调用时需要持有 p.mu
+func (p *Phaser) terminatedLocked() bool {
+	if p.parent == nil {
+		return p.terminate
+	}
+	return p.root.IsTerminated()
+}
+
+// reconcile 根 Phaser 已经进入下一阶段时，子 Phaser 重置这一阶段的计数，调用时需要持有 p.mu
+func (p *Phaser) reconcile() {
+	if p.parent == nil {
+		return
+	}
+	// 在根 Phaser 执行 onAdvance 时注册的子 Phaser 参与的是下一阶段，阶段号比根 Phaser 大，不能重置
+	if phase := p.root.Phase(); phase > p.phase {
+		p.phase = phase
+		p.unarrived = p.parties
+	}
+}
+
+// awaitAdvance 只在根 Phaser 上调用，等待根 Phaser 的阶段号大于 phase
+// 根 Phaser 执行 onAdvance 时注册的子 Phaser 的阶段号会比根 Phaser 大 1，这时也要等待
+func (p *Phaser) awaitAdvance(ctx context.Context, phase int) (int, error) {
+	for {
+		p.mu.Lock()
+		if p.terminate {
+			p.mu.Unlock()
+			return 0, ErrTerminated
+		}
+		if p.phase > phase {
+			current := p.phase
+			p.mu.Unlock()
+			return current, nil
+		}
+		ch := p.advance
+		p.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package cyclicbarrier
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaser(t *testing.T) {
+	const parties, phases = 4, 5
+	p := NewPhaser(parties)
+
+	var (
+		wg      sync.WaitGroup
+		arrived [phases]atomic.Int32
+	)
+	for range parties {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range phases {
+				arrived[i].Add(1)
+				next, err := p.ArriveAndAwaitAdvance(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, i+1, next)
+				// 进入下一阶段时，上一阶段所有人都已经到达
+				assert.Equal(t, int32(parties), arrived[i].Load())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, phases, p.Phase())
+	assert.Equal(t, parties, p.UnarrivedParties())
+}
+
+func TestPhaserRegister(t *testing.T) {
+	p := NewPhaser(1)
+
+	// 动态注册的参与者参与当前阶段
+	phase, err := p.Register()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, phase)
+	assert.Equal(t, 2, p.RegisteredParties())
+
+	phase, err = p.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, phase)
+	assert.Equal(t, 0, p.Phase())
+
+	// 最后一个参与者到达并注销，进入下一阶段
+	phase, err = p.ArriveAndDeregister()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, phase)
+	assert.Equal(t, 1, p.Phase())
+	assert.Equal(t, 1, p.RegisteredParties())
+
+	// 最后一个参与者注销，默认的 onAdvance 终止 Phaser
+	_, err = p.ArriveAndDeregister()
+	assert.NoError(t, err)
+	assert.True(t, p.IsTerminated())
+	_, err = p.Register()
+	assert.ErrorIs(t, err, ErrTerminated)
+	_, err = p.AwaitAdvance(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrTerminated)
+}
+
+func TestPhaserUnregisteredArrive(t *testing.T) {
+	p := NewPhaserWithOnAdvance(0, func(int, int) bool { return false })
+	_, err := p.Arrive()
+	assert.ErrorIs(t, err, ErrUnregisteredArrive)
+}
+
+func TestPhaserOnAdvance(t *testing.T) {
+	// 执行 3 个阶段后终止
+	var advanced []int
+	p := NewPhaserWithOnAdvance(2, func(phase, registered int) bool {
+		advanced = append(advanced, phase)
+		return phase >= 2 || registered == 0
+	})
+
+	var wg sync.WaitGroup
+	var rounds atomic.Int32
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if _, err := p.ArriveAndAwaitAdvance(context.Background()); err != nil {
+					assert.ErrorIs(t, err, ErrTerminated)
+					return
+				}
+				rounds.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.True(t, p.IsTerminated())
+	assert.Equal(t, []int{0, 1, 2}, advanced)
+	assert.Equal(t, int32(4), rounds.Load())
+}
+
+func TestPhaserOnAdvanceReentrant(t *testing.T) {
+	// onAdvance 中调用 Phaser 的方法不会死锁
+	var p *Phaser
+	var child *Phaser
+	p = NewPhaserWithOnAdvance(1, func(phase, registered int) bool {
+		assert.Equal(t, phase, p.Phase())
+		assert.False(t, p.IsTerminated())
+		assert.Equal(t, 1, child.RegisteredParties())
+		if phase == 0 {
+			// 注册的参与者参与下一阶段
+			next, err := p.Register()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, next)
+		}
+		return false
+	})
+	child, err := NewChildPhaser(p, 1)
+	assert.NoError(t, err)
+
+	// 子 Phaser 最后一个参与者到达时，根 Phaser 执行 onAdvance
+	_, err = p.Arrive()
+	assert.NoError(t, err)
+	_, err = child.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.Phase())
+	assert.Equal(t, 3, p.RegisteredParties())
+	assert.Equal(t, 3, p.UnarrivedParties())
+}
+
+func TestPhaserArriveAndDeregister(t *testing.T) {
+	p := NewPhaser(3)
+	_, err := p.Arrive()
+	assert.NoError(t, err)
+	_, err = p.Arrive()
+	assert.NoError(t, err)
+
+	// 最后一个参与者注销后进入下一阶段
+	phase, err := p.ArriveAndDeregister()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, phase)
+	assert.Equal(t, 1, p.Phase())
+	assert.Equal(t, 2, p.RegisteredParties())
+	assert.Equal(t, 2, p.UnarrivedParties())
+
+	// 全部注销后终止
+	_, err = p.ArriveAndDeregister()
+	assert.NoError(t, err)
+	_, err = p.ArriveAndDeregister()
+	assert.NoError(t, err)
+	assert.True(t, p.IsTerminated())
+	_, err = p.ArriveAndDeregister()
+	assert.ErrorIs(t, err, ErrTerminated)
+}
+
+func TestPhaserChildDuringOnAdvance(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	root := NewPhaserWithOnAdvance(1, func(phase, registered int) bool {
+		if phase == 0 {
+			close(entered)
+			<-release
+		}
+		return false
+	})
+
+	go func() {
+		_, err := root.Arrive()
+		assert.NoError(t, err)
+	}()
+	<-entered
+
+	// 根 Phaser 执行 onAdvance 时创建的子 Phaser 参与下一阶段
+	child, err := NewChildPhaser(root, 1)
+	assert.NoError(t, err)
+	arrived := make(chan int, 1)
+	go func() {
+		phase, err := child.Arrive()
+		assert.NoError(t, err)
+		arrived <- phase
+	}()
+	assert.Eventually(t, func() bool { return child.UnarrivedParties() == 0 }, time.Second, time.Millisecond)
+	close(release)
+	assert.Equal(t, 1, <-arrived)
+
+	// 子 Phaser 的到达只算一次，还要等根 Phaser 自己的参与者
+	assert.Equal(t, 1, root.Phase())
+	assert.Equal(t, 2, root.RegisteredParties())
+	assert.Equal(t, 1, root.UnarrivedParties())
+	assert.Equal(t, 0, child.UnarrivedParties())
+
+	_, err = root.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, root.Phase())
+	assert.Equal(t, 1, child.UnarrivedParties())
+}
+
+func TestPhaserAwaitAdvanceCancel(t *testing.T) {
+	p := NewPhaser(2)
+
+	// 取消只影响等待，已经到达的不撤销
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := p.ArriveAndAwaitAdvance(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, p.UnarrivedParties())
+
+	_, err = p.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, p.Phase())
+
+	// 当前阶段不是 phase 时立即返回
+	next, err := p.AwaitAdvance(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, next)
+}
+
+func TestPhaserTiered(t *testing.T) {
+	// 根 Phaser 下面 4 个子 Phaser，每个子 Phaser 8 个参与者
+	const children, perChild, phases = 4, 8, 5
+	root := NewPhaser(0)
+
+	var wg sync.WaitGroup
+	var arrived [phases]atomic.Int32
+	for range children {
+		child, err := NewChildPhaser(root, perChild)
+		assert.NoError(t, err)
+		assert.Equal(t, root, child.Parent())
+
+		for range perChild {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range phases {
+					arrived[i].Add(1)
+					next, err := child.ArriveAndAwaitAdvance(context.Background())
+					assert.NoError(t, err)
+					assert.Equal(t, i+1, next)
+					assert.Equal(t, int32(children*perChild), arrived[i].Load())
+				}
+			}()
+		}
+	}
+	// 每个子 Phaser 在根 Phaser 上算一个参与者
+	assert.Equal(t, children, root.RegisteredParties())
+	wg.Wait()
+	assert.Equal(t, phases, root.Phase())
+}
+
+func TestPhaserTieredDeregister(t *testing.T) {
+	root := NewPhaser(1)
+	child, err := NewChildPhaser(root, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, root.RegisteredParties())
+
+	// 子 Phaser 的参与者全部注销后，子 Phaser 从根 Phaser 注销
+	_, err = child.ArriveAndDeregister()
+	assert.NoError(t, err)
+	_, err = child.ArriveAndDeregister()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, root.RegisteredParties())
+	assert.Equal(t, 0, root.Phase())
+
+	_, err = root.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, root.Phase())
+
+	// 再次注册时重新注册到根 Phaser
+	phase, err := child.Register()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, phase)
+	assert.Equal(t, 2, root.RegisteredParties())
+
+	// 子 Phaser 这一阶段已经到达，再到达要等根 Phaser 进入下一阶段
+	_, err = child.Arrive()
+	assert.NoError(t, err)
+	done := make(chan int, 1)
+	go func() {
+		phase, err := child.Arrive()
+		assert.NoError(t, err)
+		done <- phase
+	}()
+	select {
+	case <-done:
+		t.Fatal("arrive should wait for the next phase")
+	case <-time.After(20 * time.Millisecond):
+	}
+	_, err = root.Arrive()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, <-done)
+}
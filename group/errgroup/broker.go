@@ -0,0 +1,151 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"concurrence/cond"
+)
+
+// Broker 是内存中的消息队列，代替 RabbitMQ 用于演示和测试，实现了 Source
+// 收到的消息在 Ack 之前处于未确认状态；Nack(true) 重新放回队尾，Nack(false) 放进死信队列
+// Close 之后不能再 Publish，队列中的消息和未确认的消息都处理完后 Receive 返回 ErrSourceClosed
+
+var (
+	// ErrBrokerClosed Broker 已经关闭
+	ErrBrokerClosed = errors.New("errgroup: broker closed")
+	// ErrSettled 消息已经 Ack 或者 Nack 过了
+	ErrSettled = errors.New("errgroup: message already settled")
+)
+
+// Broker 内存消息队列
+type Broker struct {
+	mu      sync.Mutex
+	cond    *cond.Cond
+	queue   []*entry
+	unacked int
+	closed  bool
+	dead    [][]byte
+}
+
+// entry 队列中的一条消息
+type entry struct {
+	body     []byte
+	attempts int
+}
+
+// delivery 一次投递，同一条消息重新投递时是新的 delivery，旧的 Ack 不会影响新的投递
+type delivery struct {
+	b       *Broker
+	e       *entry
+	attempt int
+	settled bool
+}
+
+// NewBroker 创建一个内存消息队列
+func NewBroker() *Broker {
+	b := &Broker{}
+	b.cond = cond.NewCond(&b.mu)
+	return b
+}
+
+// Publish 发布一条消息
+func (b *Broker) Publish(body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	b.queue = append(b.queue, &entry{body: body})
+	b.cond.Signal()
+	return nil
+}
+
+// Close 关闭 Broker，不再接受新消息
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+}
+
+// Receive 取出一条消息，队列为空时阻塞
+func (b *Broker) Receive(ctx context.Context) (Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.queue) == 0 {
+		if b.closed && b.unacked == 0 {
+			return nil, ErrSourceClosed
+		}
+		if err := b.cond.WaitContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	e := b.queue[0]
+	b.queue[0] = nil
+	b.queue = b.queue[1:]
+	e.attempts++
+	b.unacked++
+	return &delivery{b: b, e: e, attempt: e.attempts}, nil
+}
+
+// Len 返回队列中等待投递的消息数量
+func (b *Broker) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.queue)
+}
+
+// Unacked 返回已经投递、还没有确认的消息数量
+func (b *Broker) Unacked() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.unacked
+}
+
+// DeadLetters 返回死信队列中的消息
+func (b *Broker) DeadLetters() [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]byte(nil), b.dead...)
+}
+
+func (d *delivery) Body() []byte {
+	return d.e.body
+}
+
+func (d *delivery) Attempt() int {
+	return d.attempt
+}
+
+func (d *delivery) Ack() error {
+	return d.settle(func() {})
+}
+
+func (d *delivery) Nack(requeue bool) error {
+	return d.settle(func() {
+		if requeue {
+			d.b.queue = append(d.b.queue, d.e)
+		} else {
+			d.b.dead = append(d.b.dead, d.e.body)
+		}
+	})
+}
+
+func (d *delivery) settle(f func()) error {
+	b := d.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if d.settled {
+		return ErrSettled
+	}
+	d.settled = true
+	b.unacked--
+	f()
+	// 可能有消息重新入队，也可能所有消息都处理完了，唤醒所有 Receive 重新检查
+	b.cond.Broadcast()
+	return nil
+}
@@ -0,0 +1,72 @@
+package errgroup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker(t *testing.T) {
+	b := NewBroker()
+	assert.NoError(t, b.Publish([]byte("a")))
+	assert.NoError(t, b.Publish([]byte("b")))
+
+	m1, err := b.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(m1.Body()))
+	assert.Equal(t, 1, m1.Attempt())
+	assert.Equal(t, 1, b.Unacked())
+
+	// 重新入队的消息排到队尾，尝试次数加 1
+	assert.NoError(t, m1.Nack(true))
+	assert.ErrorIs(t, m1.Ack(), ErrSettled)
+	m2, err := b.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(m2.Body()))
+	m3, err := b.Receive(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(m3.Body()))
+	assert.Equal(t, 2, m3.Attempt())
+
+	assert.NoError(t, m2.Ack())
+	assert.NoError(t, m3.Nack(false))
+	assert.Equal(t, [][]byte{[]byte("a")}, b.DeadLetters())
+	assert.Equal(t, 0, b.Unacked())
+	assert.Equal(t, 0, b.Len())
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker()
+	assert.NoError(t, b.Publish([]byte("a")))
+	m, err := b.Receive(context.Background())
+	assert.NoError(t, err)
+	b.Close()
+	assert.ErrorIs(t, b.Publish([]byte("b")), ErrBrokerClosed)
+
+	// 还有未确认的消息，它可能重新入队，Receive 要等它确认
+	got := make(chan Message, 1)
+	go func() {
+		m, err := b.Receive(context.Background())
+		assert.NoError(t, err)
+		got <- m
+	}()
+	assert.NoError(t, m.Nack(true))
+	m = <-got
+	assert.Equal(t, 2, m.Attempt())
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := b.Receive(context.Background())
+		errs <- err
+	}()
+	assert.NoError(t, m.Ack())
+	assert.ErrorIs(t, <-errs, ErrSourceClosed)
+
+	// 队列为空时 Receive 可以被取消
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = NewBroker().Receive(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,139 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Consumer 用 errgroup 的 SetLimit 控制同时处理的消息数量
+// 从 Source 取出一条消息后先 TryGo，没有空闲的位置时记一次 Throttled，再用 Go 阻塞等待，
+// 所以最多只有一条消息取出来了还在等待处理，其他消息留在队列里，可以被其他消费者取走
+// 处理成功 Ack，失败或者 panic 时 Nack：尝试次数没超过 MaxAttempts 时重新入队，否则进入死信队列
+
+// ErrSourceClosed Source 已经关闭，没有更多的消息
+var ErrSourceClosed = errors.New("errgroup: source closed")
+
+// Message 一条消息
+type Message interface {
+	Body() []byte
+	// Attempt 第几次投递，从 1 开始
+	Attempt() int
+	Ack() error
+	// Nack 处理失败，requeue 为 true 时重新投递，否则丢弃或进入死信队列
+	Nack(requeue bool) error
+}
+
+// Source 消息来源，比如 RabbitMQ 的队列
+type Source interface {
+	// Receive 取出一条消息，没有消息时阻塞；关闭后返回 ErrSourceClosed
+	Receive(ctx context.Context) (Message, error)
+}
+
+// Handler 处理一条消息
+type Handler func(ctx context.Context, msg Message) error
+
+// ConsumerOption Consumer 的选项
+type ConsumerOption func(*consumerOptions)
+
+type consumerOptions struct {
+	concurrency int
+	maxAttempts int
+}
+
+// WithConcurrency 同时处理的消息数量，默认 1
+func WithConcurrency(n int) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithMaxAttempts 一条消息最多处理几次，超过后不再重新入队，默认 3
+func WithMaxAttempts(n int) ConsumerOption {
+	return func(o *consumerOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// Consumer 并发受限的消费者
+type Consumer struct {
+	src     Source
+	handler Handler
+	opts    consumerOptions
+
+	acked     atomic.Int64
+	nacked    atomic.Int64
+	throttled atomic.Int64
+}
+
+// NewConsumer 创建一个消费者
+func NewConsumer(src Source, handler Handler, opts ...ConsumerOption) *Consumer {
+	o := consumerOptions{concurrency: 1, maxAttempts: 3}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Consumer{src: src, handler: handler, opts: o}
+}
+
+// Run 消费消息直到 Source 关闭或者 ctx 被取消，返回前等待所有正在处理的消息
+// Source 关闭时返回 nil；ctx 被取消时返回 ctx.Err()；Ack/Nack 失败时停止消费并返回这个错误
+func (c *Consumer) Run(ctx context.Context) error {
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(c.opts.concurrency)
+
+	for {
+		msg, err := c.src.Receive(ctx)
+		if err != nil {
+			// Ack/Nack 失败会取消 ctx，这时返回真正的原因
+			if werr := eg.Wait(); werr != nil || errors.Is(err, ErrSourceClosed) {
+				return werr
+			}
+			return err
+		}
+
+		task := func() error { return c.handle(ctx, msg) }
+		if !eg.TryGo(task) {
+			c.throttled.Add(1)
+			eg.Go(task)
+		}
+	}
+}
+
+// Acked 返回处理成功的消息数量
+func (c *Consumer) Acked() int64 {
+	return c.acked.Load()
+}
+
+// Nacked 返回处理失败的消息数量，同一条消息每失败一次算一次
+func (c *Consumer) Nacked() int64 {
+	return c.nacked.Load()
+}
+
+// Throttled 返回取出消息时没有空闲位置、需要等待的次数
+func (c *Consumer) Throttled() int64 {
+	return c.throttled.Load()
+}
+
+func (c *Consumer) handle(ctx context.Context, msg Message) error {
+	if err := c.call(ctx, msg); err != nil {
+		c.nacked.Add(1)
+		if nerr := msg.Nack(msg.Attempt() < c.opts.maxAttempts); nerr != nil {
+			return fmt.Errorf("nack message: %w", nerr)
+		}
+		return nil
+	}
+
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("ack message: %w", err)
+	}
+	c.acked.Add(1)
+	return nil
+}
+
+// call 执行 handler，panic 时和 Group.Go 一样转换成带调用栈的 *PanicError
+func (c *Consumer) call(ctx context.Context, msg Message) error {
+	return run(func() error { return c.handler(ctx, msg) })
+}
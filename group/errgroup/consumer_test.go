@@ -0,0 +1,126 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumer(t *testing.T) {
+	b := NewBroker()
+	const total = 50
+	for i := range total {
+		assert.NoError(t, b.Publish([]byte(fmt.Sprint(i))))
+	}
+	b.Close()
+
+	var (
+		running, peak atomic.Int32
+		mu            sync.Mutex
+		handled       = map[string]int{}
+	)
+	c := NewConsumer(b, func(ctx context.Context, msg Message) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		handled[string(msg.Body())]++
+		mu.Unlock()
+		return nil
+	}, WithConcurrency(4))
+
+	assert.NoError(t, c.Run(context.Background()))
+	assert.Len(t, handled, total)
+	assert.Equal(t, int64(total), c.Acked())
+	assert.Equal(t, int32(4), peak.Load())
+	assert.Positive(t, c.Throttled())
+}
+
+func TestConsumerRetry(t *testing.T) {
+	b := NewBroker()
+	assert.NoError(t, b.Publish([]byte("ok")))
+	assert.NoError(t, b.Publish([]byte("flaky")))
+	assert.NoError(t, b.Publish([]byte("bad")))
+	assert.NoError(t, b.Publish([]byte("panic")))
+	b.Close()
+
+	c := NewConsumer(b, func(ctx context.Context, msg Message) error {
+		switch string(msg.Body()) {
+		case "flaky":
+			// 第二次成功
+			if msg.Attempt() < 2 {
+				return errors.New("flaky")
+			}
+		case "bad":
+			return errors.New("bad")
+		case "panic":
+			panic("boom")
+		}
+		return nil
+	}, WithConcurrency(2), WithMaxAttempts(3))
+
+	assert.NoError(t, c.Run(context.Background()))
+	assert.Equal(t, int64(2), c.Acked())
+	// flaky 失败 1 次，bad 和 panic 各失败 3 次
+	assert.Equal(t, int64(7), c.Nacked())
+	assert.ElementsMatch(t, [][]byte{[]byte("bad"), []byte("panic")}, b.DeadLetters())
+}
+
+func TestConsumerPanic(t *testing.T) {
+	c := NewConsumer(NewBroker(), func(ctx context.Context, msg Message) error {
+		panic("boom")
+	})
+	err := c.call(context.Background(), nil)
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, "boom", pe.Value)
+	assert.Contains(t, string(pe.Stack), "consumer_test.go")
+}
+
+// doubleAck 处理前就被确认的消息，Ack 会失败
+type doubleAck struct{ Message }
+
+func (m doubleAck) Ack() error { return ErrSettled }
+
+type doubleAckSource struct{ *Broker }
+
+func (s doubleAckSource) Receive(ctx context.Context) (Message, error) {
+	m, err := s.Broker.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return doubleAck{m}, nil
+}
+
+func TestConsumerStop(t *testing.T) {
+	// ctx 被取消时等待正在处理的消息后返回
+	b := NewBroker()
+	assert.NoError(t, b.Publish([]byte("a")))
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewConsumer(b, func(ctx context.Context, msg Message) error {
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, c.Run(ctx), context.Canceled)
+	assert.Equal(t, int64(1), c.Acked())
+
+	// Ack 失败时停止消费并返回这个错误
+	b = NewBroker()
+	assert.NoError(t, b.Publish([]byte("a")))
+	c = NewConsumer(doubleAckSource{b}, func(ctx context.Context, msg Message) error { return nil })
+	err := c.Run(context.Background())
+	assert.ErrorIs(t, err, ErrSettled)
+}
@@ -1,9 +1,11 @@
 package errgroup
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"golang.org/x/sync/errgroup"
+	"sync/atomic"
 	"time"
 )
 
@@ -47,42 +49,66 @@ func ReturnFirstErrDemo() {
 
 // ReturnAllErrDemo 返回所有错误
 func ReturnAllErrDemo() {
-	// Group 只能返回第一个错误，如果需要返回所有错误，可以使用切片来收集错误
-	var eg errgroup.Group
-	result := make([]error, 3)
+	// errgroup.Group 只能返回第一个错误，以前需要用切片来收集错误
+	// 现在用 Group，Wait 返回所有失败任务的错误
+	var g Group
 
 	// 启动第一个子任务，它执行成功
-	eg.Go(func() error {
+	g.GoNamed("#1", func() error {
 		time.Sleep(500 * time.Millisecond)
 		fmt.Println("exec #1")
-		result[0] = nil // 保存执行成功或失败的结果
 		return nil
 	})
 
 	// 执行第二个子任务，它执行失败
-	eg.Go(func() error {
+	g.GoNamed("#2", func() error {
 		time.Sleep(1000 * time.Millisecond)
 		fmt.Println("exec #2")
-		result[1] = errors.New("fail to exec #2")
-		return result[1]
+		return errors.New("fail to exec #2")
 	})
 
-	// 执行第三个子任务，它执行成功
-	eg.Go(func() error {
+	// 执行第三个子任务，它也执行失败
+	g.GoNamed("#3", func() error {
 		time.Sleep(1500 * time.Millisecond)
 		fmt.Println("exec #3")
-		result[2] = nil
-		return nil
+		return errors.New("fail to exec #3")
 	})
 
-	if err := eg.Wait(); err != nil {
-		fmt.Printf("failed:%v\n", result)
+	if err := g.Wait(); err != nil {
+		fmt.Printf("failed:\n%v\n", err)
 	} else {
 		fmt.Println("succeed")
 	}
 }
 
-// todo 使用 rabbitmq 的例子
+// SetLimitDemo 用 SetLimit 限制同时处理的消息数量
 func SetLimitDemo() {
+	// 用内存中的 Broker 代替 RabbitMQ
+	broker := NewBroker()
+	for i := range 10 {
+		_ = broker.Publish([]byte(fmt.Sprintf("msg #%d", i)))
+	}
+	broker.Close()
+
+	// 最多同时处理 3 条消息，#3 总是失败，尝试 2 次后进入死信队列
+	var running atomic.Int32
+	consumer := NewConsumer(broker, func(ctx context.Context, msg Message) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		fmt.Printf("handle %s, attempt %d, running %d\n", msg.Body(), msg.Attempt(), n)
+		time.Sleep(100 * time.Millisecond)
+		if string(msg.Body()) == "msg #3" {
+			return errors.New("fail to handle msg #3")
+		}
+		return nil
+	}, WithConcurrency(3), WithMaxAttempts(2))
 
+	if err := consumer.Run(context.Background()); err != nil {
+		fmt.Println("failed:", err)
+		return
+	}
+	fmt.Printf("acked: %d, nacked: %d, throttled: %d\n", consumer.Acked(), consumer.Nacked(), consumer.Throttled())
+	for _, body := range broker.DeadLetters() {
+		fmt.Printf("dead letter: %s\n", body)
+	}
 }
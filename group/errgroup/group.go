@@ -0,0 +1,163 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"concurrence/internal/panics"
+)
+
+// x/sync/errgroup 的 Wait 只返回第一个错误，ReturnAllErrDemo 只能自己用切片收集
+// Group 的用法和 errgroup.Group 一样，Wait 返回所有失败任务的 errors.Join，每个错误都是 *TaskError，带着任务的序号和名字
+// 任务 panic 时不会让整个进程崩溃，而是转换成 *PanicError
+// 通过 WithContext 创建时，第一个失败的任务会取消 ctx（fail-fast），但 Wait 仍然等待所有任务并返回所有错误
+
+// TaskError 一个任务的错误
+type TaskError struct {
+	Index int    // 任务的序号，按调用 Go 的顺序从 0 开始
+	Name  string // GoNamed 指定的名字
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("task %d (%s): %v", e.Index, e.Name, e.Err)
+	}
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+// Unwrap 返回任务的错误
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError 任务 panic 时的值和调用栈
+type PanicError = panics.Error
+
+// Group 收集所有错误的任务组，零值可用
+type Group struct {
+	cancel func(error)
+
+	wg  sync.WaitGroup
+	sem chan struct{}
+
+	mu    sync.Mutex
+	next  int
+	errs  []error
+	first *TaskError // 第一个失败的任务
+}
+
+// WithContext 创建一个 Group，第一个任务失败时取消返回的 ctx，取消的原因是这个任务的 *TaskError
+// Wait 返回时也会取消 ctx
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// SetLimit 限制同时执行的任务数量，n 为负数表示不限制
+// 和 errgroup 一样，有任务在执行时不能修改
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("errgroup: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go 在新的 goroutine 中执行 f，达到并发限制时阻塞
+func (g *Group) Go(f func() error) {
+	g.GoNamed("", f)
+}
+
+// GoNamed 和 Go 一样，name 会出现在任务的 *TaskError 里
+func (g *Group) GoNamed(name string, f func() error) {
+	g.goIndexed(name, func(int) error { return f() })
+}
+
+// goIndexed 和 GoNamed 一样，f 的参数是任务的序号
+func (g *Group) goIndexed(name string, f func(index int) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(name, f)
+}
+
+// TryGo 达到并发限制时不执行 f，返回 false
+func (g *Group) TryGo(f func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start("", func(int) error { return f() })
+	return true
+}
+
+// Wait 等待所有任务执行完成，返回所有失败任务的错误，按任务序号排序，没有失败时返回 nil
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(nil)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sort.Slice(g.errs, func(i, j int) bool {
+		return g.errs[i].(*TaskError).Index < g.errs[j].(*TaskError).Index
+	})
+	return errors.Join(g.errs...)
+}
+
+// start 执行 f，参数是任务的序号
+func (g *Group) start(name string, f func(index int) error) {
+	g.mu.Lock()
+	index := g.next
+	g.next++
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer func() {
+			if g.sem != nil {
+				<-g.sem
+			}
+			g.wg.Done()
+		}()
+
+		if err := run(func() error { return f(index) }); err != nil {
+			g.fail(&TaskError{Index: index, Name: name, Err: err})
+		}
+	}()
+}
+
+func (g *Group) fail(err *TaskError) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	if g.first == nil {
+		g.first = err
+	}
+	g.mu.Unlock()
+
+	if g.cancel != nil {
+		// 只有第一次取消生效
+		g.cancel(err)
+	}
+}
+
+// run 执行 f，把 panic 转换成 *PanicError
+func run(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panics.New(v)
+		}
+	}()
+	return f()
+}
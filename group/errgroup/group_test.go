@@ -0,0 +1,112 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupAllErrors(t *testing.T) {
+	err1 := errors.New("fail #1")
+	err3 := errors.New("fail #3")
+
+	var g Group
+	g.Go(func() error { return nil })
+	g.GoNamed("second", func() error {
+		time.Sleep(20 * time.Millisecond)
+		return err1
+	})
+	g.Go(func() error { return nil })
+	g.Go(func() error { return err3 })
+
+	err := g.Wait()
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err3)
+
+	// 按任务序号排序，带着序号和名字
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	assert.Len(t, errs, 2)
+	assert.Equal(t, &TaskError{Index: 1, Name: "second", Err: err1}, errs[0])
+	assert.Equal(t, &TaskError{Index: 3, Err: err3}, errs[1])
+	assert.Equal(t, "task 1 (second): fail #1\ntask 3: fail #3", err.Error())
+
+	var g2 Group
+	g2.Go(func() error { return nil })
+	assert.NoError(t, g2.Wait())
+}
+
+func TestGroupWithContext(t *testing.T) {
+	errFirst := errors.New("first")
+	g, ctx := WithContext(context.Background())
+
+	g.Go(func() error { return errFirst })
+	// 第一个失败取消 ctx，其他任务据此提前结束
+	g.Go(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := g.Wait()
+	assert.ErrorIs(t, err, errFirst)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	var te *TaskError
+	assert.ErrorAs(t, context.Cause(ctx), &te)
+	assert.Equal(t, 0, te.Index)
+
+	// 全部成功时 Wait 返回后也取消 ctx
+	g, ctx = WithContext(context.Background())
+	g.Go(func() error { return nil })
+	assert.NoError(t, g.Wait())
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
+
+func TestGroupLimit(t *testing.T) {
+	var g Group
+	g.SetLimit(2)
+
+	var running, peak atomic.Int32
+	for range 10 {
+		g.Go(func() error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	assert.NoError(t, g.Wait())
+	assert.Equal(t, int32(2), peak.Load())
+
+	// 达到限制时 TryGo 返回 false
+	release := make(chan struct{})
+	assert.True(t, g.TryGo(func() error { <-release; return nil }))
+	assert.True(t, g.TryGo(func() error { <-release; return nil }))
+	assert.False(t, g.TryGo(func() error { return nil }))
+	assert.Panics(t, func() { g.SetLimit(3) })
+	close(release)
+	assert.NoError(t, g.Wait())
+}
+
+func TestGroupPanic(t *testing.T) {
+	errBoom := errors.New("boom")
+	var g Group
+	g.Go(func() error { panic(errBoom) })
+	g.Go(func() error { panic("oops") })
+
+	err := g.Wait()
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, errBoom)
+	assert.Contains(t, err.Error(), "panic: oops")
+	assert.Contains(t, string(pe.Stack), "TestGroupPanic")
+}
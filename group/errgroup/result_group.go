@@ -0,0 +1,149 @@
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// 用 Group 收集结果时，每个任务都要自己往按序号分配的切片里写结果
+// ResultGroup 的任务直接返回结果，Wait 按提交的顺序返回所有结果
+// 默认收集所有错误，和 Group 一样；WithFailFast 时第一个错误取消 ctx，Wait 只返回这个错误
+// WithStream 时每个任务完成后立即把结果发到 Results 返回的 channel
+
+// Result 流式模式下一个任务的结果
+type Result[T any] struct {
+	Index int // 任务的序号，按调用 Go 的顺序从 0 开始
+	Value T
+	Err   error
+}
+
+// ResultOption ResultGroup 的选项
+type ResultOption func(*resultOptions)
+
+type resultOptions struct {
+	limit    int
+	failFast bool
+	stream   bool
+	buffer   int
+}
+
+// WithLimit 限制同时执行的任务数量
+func WithLimit(n int) ResultOption {
+	return func(o *resultOptions) {
+		o.limit = n
+	}
+}
+
+// WithFailFast 第一个任务失败时取消 ctx，Wait 只返回第一个错误
+func WithFailFast() ResultOption {
+	return func(o *resultOptions) {
+		o.failFast = true
+	}
+}
+
+// WithStream 每个任务完成后把结果发到 Results 返回的 channel，buffer 是 channel 的容量
+// channel 满了任务会阻塞，调用者需要一边 Wait 一边读取
+func WithStream(buffer int) ResultOption {
+	return func(o *resultOptions) {
+		o.stream = true
+		o.buffer = buffer
+	}
+}
+
+// ResultGroup 按提交顺序收集结果的任务组
+type ResultGroup[T any] struct {
+	g        *Group
+	ctx      context.Context
+	failFast bool
+	stream   chan Result[T]
+
+	mu      sync.Mutex
+	results []T
+	closed  bool // stream 已经关闭
+}
+
+// NewResultGroup 创建一个 ResultGroup，任务的 ctx 派生自 ctx
+func NewResultGroup[T any](ctx context.Context, opts ...ResultOption) *ResultGroup[T] {
+	o := resultOptions{limit: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	rg := &ResultGroup[T]{failFast: o.failFast}
+	if o.failFast {
+		rg.g, rg.ctx = WithContext(ctx)
+	} else {
+		rg.g, rg.ctx = &Group{}, ctx
+	}
+	rg.g.SetLimit(o.limit)
+	if o.stream {
+		rg.stream = make(chan Result[T], o.buffer)
+	}
+	return rg
+}
+
+// Go 在新的 goroutine 中执行 f，达到并发限制时阻塞
+// 流式模式下 Wait 关闭 channel 之后不能再调用 Go，否则 panic
+func (rg *ResultGroup[T]) Go(f func(ctx context.Context) (T, error)) {
+	rg.mu.Lock()
+	closed := rg.closed
+	rg.mu.Unlock()
+	if closed {
+		panic("errgroup: ResultGroup.Go called after Wait closed the result stream")
+	}
+
+	rg.g.goIndexed("", func(index int) error {
+		var v T
+		err := run(func() (err error) {
+			v, err = f(rg.ctx)
+			return err
+		})
+
+		rg.mu.Lock()
+		if index >= len(rg.results) {
+			rg.results = append(rg.results, make([]T, index+1-len(rg.results))...)
+		}
+		rg.results[index] = v
+		rg.mu.Unlock()
+
+		if rg.stream != nil {
+			rg.stream <- Result[T]{Index: index, Value: v, Err: err}
+		}
+		return err
+	})
+}
+
+// Results 返回流式模式的结果 channel，Wait 返回时关闭，之后不能再调用 Go；没有使用 WithStream 时返回 nil
+func (rg *ResultGroup[T]) Results() <-chan Result[T] {
+	return rg.stream
+}
+
+// Wait 等待所有任务执行完成，按提交顺序返回结果，失败的任务的结果是它返回的值
+// 默认返回所有失败任务的错误，WithFailFast 时只返回第一个错误
+// 可以多次调用，和 Group.Wait 一样，每次返回相同的结果
+func (rg *ResultGroup[T]) Wait() ([]T, error) {
+	err := rg.g.Wait()
+	if rg.stream != nil {
+		rg.mu.Lock()
+		if !rg.closed {
+			rg.closed = true
+			close(rg.stream)
+		}
+		rg.mu.Unlock()
+	}
+
+	rg.g.mu.Lock()
+	n, first := rg.g.next, rg.g.first
+	rg.g.mu.Unlock()
+	if rg.failFast && first != nil {
+		err = first
+	}
+
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+	results := rg.results
+	if len(results) < n {
+		results = append(results, make([]T, n-len(results))...)
+	}
+	return results, err
+}
@@ -0,0 +1,121 @@
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultGroupOrder(t *testing.T) {
+	rg := NewResultGroup[int](context.Background(), WithLimit(3))
+	for i := range 10 {
+		rg.Go(func(ctx context.Context) (int, error) {
+			// 后提交的先完成，结果仍然按提交顺序
+			time.Sleep(time.Duration(10-i) * time.Millisecond)
+			return i * i, nil
+		})
+	}
+
+	results, err := rg.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 4, 9, 16, 25, 36, 49, 64, 81}, results)
+}
+
+func TestResultGroupCollectAll(t *testing.T) {
+	err1 := errors.New("fail #1")
+	err2 := errors.New("fail #2")
+
+	rg := NewResultGroup[string](context.Background())
+	rg.Go(func(ctx context.Context) (string, error) { return "a", nil })
+	rg.Go(func(ctx context.Context) (string, error) { return "", err1 })
+	rg.Go(func(ctx context.Context) (string, error) {
+		// 默认不取消 ctx
+		time.Sleep(10 * time.Millisecond)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "c", nil
+	})
+	rg.Go(func(ctx context.Context) (string, error) { panic(err2) })
+
+	results, err := rg.Wait()
+	assert.Equal(t, []string{"a", "", "c", ""}, results)
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err2)
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+}
+
+func TestResultGroupFailFast(t *testing.T) {
+	errFirst := errors.New("first")
+
+	rg := NewResultGroup[int](context.Background(), WithFailFast())
+	rg.Go(func(ctx context.Context) (int, error) { return 1, nil })
+	rg.Go(func(ctx context.Context) (int, error) { return 0, errFirst })
+	rg.Go(func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return -1, ctx.Err()
+	})
+
+	// 只返回第一个错误，被取消的任务的错误不返回
+	results, err := rg.Wait()
+	assert.Equal(t, []int{1, 0, -1}, results)
+	var te *TaskError
+	assert.ErrorAs(t, err, &te)
+	assert.Equal(t, 1, te.Index)
+	assert.ErrorIs(t, err, errFirst)
+	assert.NotErrorIs(t, err, context.Canceled)
+}
+
+func TestResultGroupStream(t *testing.T) {
+	rg := NewResultGroup[int](context.Background(), WithStream(0), WithLimit(2))
+
+	errOdd := errors.New("odd")
+	go func() {
+		for i := range 6 {
+			rg.Go(func(ctx context.Context) (int, error) {
+				if i%2 == 1 {
+					return 0, errOdd
+				}
+				return i, nil
+			})
+		}
+		_, _ = rg.Wait()
+	}()
+
+	// 按完成的顺序收到结果，Wait 返回后 channel 关闭
+	var got []Result[int]
+	for r := range rg.Results() {
+		got = append(got, r)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Index < got[j].Index })
+	assert.Len(t, got, 6)
+	for i, r := range got {
+		assert.Equal(t, i, r.Index)
+		if i%2 == 1 {
+			assert.ErrorIs(t, r.Err, errOdd)
+		} else {
+			assert.NoError(t, r.Err)
+			assert.Equal(t, i, r.Value)
+		}
+	}
+
+	// channel 读完之后再次 Wait 不会 panic，返回相同的结果
+	vals, err := rg.Wait()
+	assert.Equal(t, []int{0, 0, 2, 0, 4, 0}, vals)
+	assert.ErrorIs(t, err, errOdd)
+	vals2, err2 := rg.Wait()
+	assert.Equal(t, vals, vals2)
+	assert.Equal(t, err, err2)
+
+	// channel 已经关闭，不能再提交任务
+	assert.Panics(t, func() {
+		rg.Go(func(ctx context.Context) (int, error) { return 0, nil })
+	})
+
+	assert.Nil(t, NewResultGroup[int](context.Background()).Results())
+}
@@ -2,7 +2,6 @@ package schedgroup
 
 import (
 	"context"
-	"github.com/mdlayher/schedgroup"
 	"log"
 	"time"
 )
@@ -13,22 +12,29 @@ import (
 // 少量子任务用 timer 就可以实现
 // 大量子任务，且要能撤销，用 timer CPU 资源消耗就大了
 // schedgroup 用 heap 结构按子任务执行时间排序，避免使用大量 timer
+// mdlayher/schedgroup 不能撤销单个任务，这里换成了自己实现的 Scheduler
 
 func SchedGroupDemo() {
-	sg := schedgroup.New(context.Background())
+	s := NewScheduler(context.Background())
+	defer s.Stop()
 
 	// 设置子任务分别在 100ms、200ms、300ms 后执行
 	for i := 0; i < 3; i++ {
 		n := i + 1
-		sg.Delay(time.Duration(n*100)*time.Millisecond, func() {
+		s.Delay(time.Duration(n*100)*time.Millisecond, func() {
 			log.Println(n) // 输出任务编号
 		})
 	}
 
+	// 第四个子任务被撤销，不会执行
+	t := s.Delay(400*time.Millisecond, func() {
+		log.Println(4)
+	})
+	t.Cancel()
+
 	// 等待所有子任务完成
-	// 调用了 Wait 方法就不能再调 Delay 和 Schedule 方法了
-	// Wait 方法只能调一次
-	if err := sg.Wait(); err != nil {
+	// 和 mdlayher/schedgroup 不同，Wait 之后还可以继续 Delay 和 Schedule
+	if err := s.Wait(); err != nil {
 		log.Fatalf("failed to wait: %v", err)
 	}
 }
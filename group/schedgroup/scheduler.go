@@ -0,0 +1,308 @@
+package schedgroup
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+	"concurrence/cond"
+)
+
+// mdlayher/schedgroup 的任务一旦 Delay 就不能撤销，调用 Wait 之后也不能再添加任务
+// Scheduler 同样用一个最小堆按执行时间排序，只有一个 goroutine 和一个 timer 等待堆顶的任务到期，
+// 到期的任务交给固定数量的 worker 执行，大量任务时不会创建大量 goroutine 和 timer
+// Schedule/Delay 返回 *Task，可以 Cancel 撤销或者 Reschedule 修改执行时间
+// Wait 等待当前所有任务执行完成，之后还可以继续添加任务
+
+// Option Scheduler 的选项
+type Option func(*options)
+
+type options struct {
+	workers int
+	clock   clock.Clock
+}
+
+// WithWorkers 执行任务的 worker 数量，默认 1
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithClock 设置使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+type taskState int
+
+const (
+	taskScheduled taskState = iota
+	taskRunning
+	taskDone
+	taskCanceled
+)
+
+// Task 调度的任务
+type Task struct {
+	s     *Scheduler
+	fn    func()
+	at    time.Time
+	seq   uint64 // 执行时间相同时按添加的顺序执行
+	index int    // 在堆中的位置
+	state taskState
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	clock  clock.Clock
+	ctx    context.Context
+	cancel context.CancelFunc
+	work   chan *Task
+	wake   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	idle    *cond.Cond // 没有等待和正在执行的任务时通知 Wait
+	tasks   taskHeap
+	seq     uint64
+	running int
+}
+
+// NewScheduler 创建一个调度器，ctx 被取消时停止调度，还没执行的任务不再执行
+func NewScheduler(ctx context.Context, opts ...Option) *Scheduler {
+	o := options{workers: 1, clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers <= 0 {
+		panic("schedgroup: workers must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &Scheduler{
+		clock:  o.clock,
+		ctx:    ctx,
+		cancel: cancel,
+		work:   make(chan *Task),
+		wake:   make(chan struct{}, 1),
+	}
+	s.idle = cond.NewCond(&s.mu)
+
+	s.wg.Add(1 + o.workers)
+	go s.loop()
+	for range o.workers {
+		go s.worker()
+	}
+	return s
+}
+
+// Schedule 在 at 时执行 fn，at 已经过去时尽快执行
+// 调度器已经停止时返回的任务不会执行
+func (s *Scheduler) Schedule(at time.Time, fn func()) *Task {
+	t := &Task{s: s, fn: fn, at: at}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		t.state = taskCanceled
+		return t
+	}
+	s.seq++
+	t.seq = s.seq
+	heap.Push(&s.tasks, t)
+	if t.index == 0 {
+		s.notify()
+	}
+	return t
+}
+
+// Delay 在 d 之后执行 fn
+func (s *Scheduler) Delay(d time.Duration, fn func()) *Task {
+	return s.Schedule(s.clock.Now().Add(d), fn)
+}
+
+// Len 返回还没有到期的任务数量
+func (s *Scheduler) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tasks)
+}
+
+// Wait 等待所有任务执行完成，包括等待期间添加的任务；调度器停止时返回 ctx.Err()
+func (s *Scheduler) Wait() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.tasks) > 0 || s.running > 0 {
+		if err := s.idle.WaitContext(s.ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop 停止调度，等待正在执行的任务完成，还没执行的任务不再执行
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Cancel 撤销任务，任务已经开始执行或者已经撤销时返回 false
+func (t *Task) Cancel() bool {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.state != taskScheduled {
+		return false
+	}
+	top := t.index == 0
+	heap.Remove(&s.tasks, t.index)
+	t.state = taskCanceled
+	if top {
+		s.notify()
+	}
+	s.signalIdle()
+	return true
+}
+
+// Reschedule 把任务的执行时间改为 at，任务已经开始执行或者已经撤销时返回 false
+func (t *Task) Reschedule(at time.Time) bool {
+	s := t.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.state != taskScheduled {
+		return false
+	}
+	top := t.index == 0
+	t.at = at
+	heap.Fix(&s.tasks, t.index)
+	if top || t.index == 0 {
+		s.notify()
+	}
+	return true
+}
+
+// When 返回任务的执行时间
+func (t *Task) When() time.Time {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	return t.at
+}
+
+// loop 等待堆顶的任务到期，把到期的任务交给 worker
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	defer close(s.work)
+
+	timer := s.clock.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		s.mu.Lock()
+		now := s.clock.Now()
+		var due []*Task
+		for len(s.tasks) > 0 && !s.tasks[0].at.After(now) {
+			t := heap.Pop(&s.tasks).(*Task)
+			t.state = taskRunning
+			s.running++
+			due = append(due, t)
+		}
+		wait := time.Duration(-1)
+		if len(s.tasks) > 0 {
+			wait = s.tasks[0].at.Sub(now)
+		}
+		s.mu.Unlock()
+
+		if len(due) > 0 {
+			for _, t := range due {
+				select {
+				case s.work <- t:
+				case <-s.ctx.Done():
+					return
+				}
+			}
+			// 交给 worker 的过程中可能又有任务到期
+			continue
+		}
+
+		var expired <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			expired = timer.C()
+		}
+		select {
+		case <-expired:
+		case <-s.wake:
+			timer.Stop()
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for t := range s.work {
+		t.fn()
+
+		s.mu.Lock()
+		t.state = taskDone
+		s.running--
+		s.signalIdle()
+		s.mu.Unlock()
+	}
+}
+
+// notify 堆顶变化时唤醒 loop 重新计算等待时间
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// signalIdle 没有任务时唤醒 Wait，调用时需要持有 s.mu
+func (s *Scheduler) signalIdle() {
+	if len(s.tasks) == 0 && s.running == 0 {
+		s.idle.Broadcast()
+	}
+}
+
+// taskHeap 按执行时间排序的最小堆
+type taskHeap []*Task
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	t := x.(*Task)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
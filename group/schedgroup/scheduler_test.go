@@ -0,0 +1,159 @@
+package schedgroup
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"concurrence/clock"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// recorder 记录任务的执行顺序
+type recorder struct {
+	mu  sync.Mutex
+	ran []int
+}
+
+func (r *recorder) task(n int) func() {
+	return func() {
+		r.mu.Lock()
+		r.ran = append(r.ran, n)
+		r.mu.Unlock()
+	}
+}
+
+func (r *recorder) get() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.ran...)
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	c := clock.NewFake(epoch)
+	s := NewScheduler(context.Background(), WithClock(c))
+	defer s.Stop()
+
+	var r recorder
+	s.Delay(3*time.Second, r.task(3))
+	s.Delay(time.Second, r.task(1))
+	s.Delay(2*time.Second, r.task(2))
+	// 执行时间相同时按添加顺序执行
+	s.Delay(2*time.Second, r.task(22))
+	c.BlockUntil(1)
+
+	c.Advance(time.Second)
+	assert.Eventually(t, func() bool { return len(r.get()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, 3, s.Len())
+
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, []int{1, 2, 22, 3}, r.get())
+
+	// 已经过去的时间立即执行
+	s.Schedule(epoch, r.task(0))
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, []int{1, 2, 22, 3, 0}, r.get())
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	c := clock.NewFake(epoch)
+	s := NewScheduler(context.Background(), WithClock(c))
+	defer s.Stop()
+
+	var r recorder
+	t1 := s.Delay(time.Second, r.task(1))
+	t2 := s.Delay(2*time.Second, r.task(2))
+
+	// 撤销堆顶的任务，loop 改为等待下一个任务
+	assert.True(t, t1.Cancel())
+	assert.False(t, t1.Cancel())
+	assert.False(t, t1.Reschedule(epoch))
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, []int{2}, r.get())
+	assert.False(t, t2.Cancel())
+
+	// 全部撤销时 Wait 立即返回
+	s.Delay(time.Second, r.task(3)).Cancel()
+	assert.NoError(t, s.Wait())
+}
+
+func TestSchedulerReschedule(t *testing.T) {
+	c := clock.NewFake(epoch)
+	s := NewScheduler(context.Background(), WithClock(c))
+	defer s.Stop()
+
+	var r recorder
+	t1 := s.Delay(time.Second, r.task(1))
+	s.Delay(2*time.Second, r.task(2))
+
+	// 推迟到第二个任务之后
+	assert.True(t, t1.Reschedule(epoch.Add(3*time.Second)))
+	assert.Equal(t, epoch.Add(3*time.Second), t1.When())
+	c.BlockUntil(1)
+	c.Advance(2 * time.Second)
+	assert.Eventually(t, func() bool { return len(r.get()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int{2}, r.get())
+
+	// 提前到现在
+	assert.True(t, t1.Reschedule(c.Now()))
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, []int{2, 1}, r.get())
+}
+
+func TestSchedulerWorkers(t *testing.T) {
+	s := NewScheduler(context.Background(), WithWorkers(4))
+	defer s.Stop()
+
+	var running, peak, total atomic.Int32
+	for range 20 {
+		s.Delay(10*time.Millisecond, func() {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			total.Add(1)
+		})
+	}
+	assert.NoError(t, s.Wait())
+	assert.Equal(t, int32(20), total.Load())
+	assert.Equal(t, int32(4), peak.Load())
+}
+
+func TestSchedulerStop(t *testing.T) {
+	c := clock.NewFake(epoch)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewScheduler(ctx, WithClock(c))
+
+	var r recorder
+	s.Delay(time.Second, r.task(1))
+	c.BlockUntil(1)
+
+	errs := make(chan error, 1)
+	go func() { errs <- s.Wait() }()
+
+	// ctx 被取消后没执行的任务不再执行，Wait 返回 ctx.Err()
+	cancel()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	s.Stop()
+	c.Advance(time.Second)
+	assert.Empty(t, r.get())
+
+	// 停止后添加的任务不会执行
+	task := s.Delay(0, r.task(2))
+	assert.False(t, task.Cancel())
+	assert.Empty(t, r.get())
+}
@@ -0,0 +1,219 @@
+package sizegroup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"concurrence/internal/panics"
+	"concurrence/semaphore"
+)
+
+// SizedGroup 和 ErrSizedGroup 的行为和 go-pkgz/syncs 一样，用 semaphore.Semaphore 限制同时执行的任务数量：
+//   - 默认：Go 立即返回，每个任务一个 goroutine，goroutine 在信号量上等待
+//   - Preemptive：Go 先获取信号量再创建 goroutine，信号量满了 Go 会阻塞，不会有大量等待的 goroutine
+//   - Discard：信号量满了直接丢弃任务，Go 不阻塞，隐含 Preemptive
+//   - TermOnErr：只对 ErrSizedGroup 有效，第一个错误之后取消 ctx，还没开始执行的任务都跳过
+//
+// ctx 被取消后，还没开始执行的任务也都跳过
+// 任务 panic 时不会让进程崩溃：ErrSizedGroup 把 panic 转换成 *PanicError，SizedGroup 在 Wait 中重新 panic
+// Discarded 和 Skipped 返回被丢弃和被跳过的任务数量
+
+// PanicError 任务 panic 时的值和调用栈
+type PanicError = panics.Error
+
+// GroupOption 选项
+type GroupOption func(o *options)
+
+type options struct {
+	ctx           context.Context
+	preLock       bool
+	termOnError   bool
+	discardIfFull bool
+}
+
+// Context 任务使用的 ctx，ctx 被取消后还没开始执行的任务都跳过
+func Context(ctx context.Context) GroupOption {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// Preemptive Go 先获取信号量再创建 goroutine，信号量满了 Go 会阻塞
+func Preemptive(o *options) {
+	o.preLock = true
+}
+
+// TermOnErr 第一个错误之后不再执行新的任务，只对 ErrSizedGroup 有效
+func TermOnErr(o *options) {
+	o.termOnError = true
+}
+
+// Discard 信号量满了直接丢弃任务，隐含 Preemptive
+func Discard(o *options) {
+	o.discardIfFull = true
+	o.preLock = true
+}
+
+// group 是 SizedGroup 和 ErrSizedGroup 共用的部分
+type group struct {
+	options
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	sema   *semaphore.Semaphore
+
+	discarded atomic.Int64
+	skipped   atomic.Int64
+}
+
+func (g *group) init(size int, opts []GroupOption) {
+	g.options.ctx = context.Background()
+	for _, opt := range opts {
+		opt(&g.options)
+	}
+	g.ctx, g.cancel = context.WithCancelCause(g.options.ctx)
+	g.sema = semaphore.NewSemaphore(size)
+}
+
+// Discarded 返回 Discard 模式下因为信号量满了被丢弃的任务数量
+func (g *group) Discarded() int64 {
+	return g.discarded.Load()
+}
+
+// Skipped 返回因为 ctx 被取消或者出错终止而没有执行的任务数量
+func (g *group) Skipped() int64 {
+	return g.skipped.Load()
+}
+
+// spawn 按选项获取信号量，在新的 goroutine 中执行 run；任务被丢弃或跳过时不执行
+func (g *group) spawn(run func()) {
+	if g.ctx.Err() != nil {
+		g.skipped.Add(1)
+		return
+	}
+
+	if g.preLock && !g.sema.TryAcquire(1) {
+		if g.discardIfFull {
+			g.discarded.Add(1)
+			return
+		}
+		if err := g.sema.AcquireContext(g.ctx, 1); err != nil {
+			g.skipped.Add(1)
+			return
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if !g.preLock {
+			if err := g.sema.AcquireContext(g.ctx, 1); err != nil {
+				g.skipped.Add(1)
+				return
+			}
+		}
+		defer g.sema.Release()
+
+		// 等待信号量期间可能已经终止了
+		if g.ctx.Err() != nil {
+			g.skipped.Add(1)
+			return
+		}
+		run()
+	}()
+}
+
+// SizedGroup 限制并发数量的 WaitGroup
+type SizedGroup struct {
+	group
+
+	panicOnce sync.Once
+	panicked  *PanicError
+}
+
+// NewSizedGroup 创建一个最多同时执行 size 个任务的 SizedGroup
+func NewSizedGroup(size int, opts ...GroupOption) *SizedGroup {
+	g := &SizedGroup{}
+	g.init(size, opts)
+	return g
+}
+
+// Go 执行 fn，参数是 Context 选项设置的 ctx
+func (g *SizedGroup) Go(fn func(ctx context.Context)) {
+	g.spawn(func() {
+		defer func() {
+			if v := recover(); v != nil {
+				pe := panics.New(v)
+				g.panicOnce.Do(func() { g.panicked = pe })
+			}
+		}()
+		fn(g.ctx)
+	})
+}
+
+// Wait 等待所有任务执行完成，有任务 panic 时在这里重新 panic 第一个 *PanicError
+// 和 errgroup 一样，Wait 返回后 ctx 被取消，之后的 Go 都会被跳过
+func (g *SizedGroup) Wait() {
+	g.wg.Wait()
+	g.cancel(nil)
+	if g.panicked != nil {
+		panic(g.panicked)
+	}
+}
+
+// ErrSizedGroup 限制并发数量、收集错误的任务组
+type ErrSizedGroup struct {
+	group
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewErrSizedGroup 创建一个最多同时执行 size 个任务的 ErrSizedGroup
+func NewErrSizedGroup(size int, opts ...GroupOption) *ErrSizedGroup {
+	g := &ErrSizedGroup{}
+	g.init(size, opts)
+	return g
+}
+
+// Go 执行 f，TermOnErr 时 f 返回错误会取消 ctx
+func (g *ErrSizedGroup) Go(f func(ctx context.Context) error) {
+	g.spawn(func() {
+		if err := run(g.ctx, f); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			if g.termOnError {
+				g.cancel(err)
+			}
+		}
+	})
+}
+
+// Wait 等待所有任务执行完成，返回所有错误的 errors.Join
+// 因为外部的 ctx 被取消而跳过了任务时，也包括 ctx 的错误
+// 和 errgroup 一样，Wait 返回后 ctx 被取消，之后的 Go 都会被跳过
+func (g *ErrSizedGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	errs := g.errs
+	if err := g.options.ctx.Err(); err != nil && g.skipped.Load() > 0 {
+		errs = append(errs[:len(errs):len(errs)], err)
+	}
+	return errors.Join(errs...)
+}
+
+// run 执行 f，把 panic 转换成 *PanicError
+func run(ctx context.Context, f func(ctx context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = panics.New(v)
+		}
+	}()
+	return f(ctx)
+}
@@ -0,0 +1,242 @@
+package sizegroup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// peak 记录同时执行的任务数量的最大值
+type peak struct {
+	running, max atomic.Int32
+}
+
+func (p *peak) enter() {
+	n := p.running.Add(1)
+	for {
+		m := p.max.Load()
+		if n <= m || p.max.CompareAndSwap(m, n) {
+			return
+		}
+	}
+}
+
+func (p *peak) leave() {
+	p.running.Add(-1)
+}
+
+func TestSizedGroup(t *testing.T) {
+	for _, opts := range [][]GroupOption{nil, {Preemptive}} {
+		g := NewSizedGroup(5, opts...)
+		var p peak
+		var total atomic.Int32
+		for range 100 {
+			g.Go(func(ctx context.Context) {
+				p.enter()
+				defer p.leave()
+				time.Sleep(time.Millisecond)
+				total.Add(1)
+			})
+		}
+		g.Wait()
+		assert.Equal(t, int32(100), total.Load())
+		assert.Equal(t, int32(5), p.max.Load())
+		assert.Zero(t, g.Discarded())
+		assert.Zero(t, g.Skipped())
+	}
+}
+
+func TestSizedGroupWaitCancel(t *testing.T) {
+	// Wait 之后取消内部的 ctx，不会一直挂在外部的 ctx 上
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var inner context.Context
+	g := NewErrSizedGroup(1, Context(parent))
+	g.Go(func(ctx context.Context) error {
+		inner = ctx
+		return nil
+	})
+	assert.NoError(t, g.Wait())
+	assert.ErrorIs(t, inner.Err(), context.Canceled)
+	assert.NoError(t, parent.Err())
+
+	s := NewSizedGroup(1, Context(parent))
+	s.Go(func(ctx context.Context) { inner = ctx })
+	s.Wait()
+	assert.ErrorIs(t, inner.Err(), context.Canceled)
+}
+
+func TestSizedGroupPreemptive(t *testing.T) {
+	g := NewSizedGroup(2, Preemptive)
+	release := make(chan struct{})
+	for range 2 {
+		g.Go(func(ctx context.Context) { <-release })
+	}
+
+	// 信号量满了，Go 阻塞
+	spawned := make(chan struct{})
+	go func() {
+		g.Go(func(ctx context.Context) {})
+		close(spawned)
+	}()
+	select {
+	case <-spawned:
+		t.Fatal("Go should block while the group is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-spawned
+	g.Wait()
+}
+
+func TestSizedGroupDiscard(t *testing.T) {
+	g := NewSizedGroup(2, Discard)
+	release := make(chan struct{})
+	var ran atomic.Int32
+	for range 5 {
+		// 不阻塞，满了直接丢弃
+		g.Go(func(ctx context.Context) {
+			ran.Add(1)
+			<-release
+		})
+	}
+	close(release)
+	g.Wait()
+	assert.Equal(t, int32(2), ran.Load())
+	assert.Equal(t, int64(3), g.Discarded())
+}
+
+func TestSizedGroupContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewSizedGroup(1, Context(ctx))
+
+	started := make(chan struct{})
+	g.Go(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	<-started
+	// 这个任务在信号量上等待，ctx 被取消后跳过
+	g.Go(func(ctx context.Context) { t.Error("should be skipped") })
+	cancel()
+	g.Wait()
+
+	g.Go(func(ctx context.Context) { t.Error("should be skipped") })
+	assert.Equal(t, int64(2), g.Skipped())
+}
+
+func TestSizedGroupPanic(t *testing.T) {
+	g := NewSizedGroup(2)
+	g.Go(func(ctx context.Context) { panic("boom") })
+	g.Go(func(ctx context.Context) {})
+
+	defer func() {
+		pe, ok := recover().(*PanicError)
+		assert.True(t, ok)
+		assert.Equal(t, "boom", pe.Value)
+	}()
+	g.Wait()
+}
+
+func TestErrSizedGroup(t *testing.T) {
+	err1 := errors.New("fail #1")
+	err2 := errors.New("fail #2")
+
+	// 默认收集所有错误，不终止
+	g := NewErrSizedGroup(3)
+	var ran atomic.Int32
+	for i := range 10 {
+		g.Go(func(ctx context.Context) error {
+			ran.Add(1)
+			switch i {
+			case 1:
+				return err1
+			case 5:
+				return err2
+			case 7:
+				panic("boom")
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	assert.Equal(t, int32(10), ran.Load())
+	assert.ErrorIs(t, err, err1)
+	assert.ErrorIs(t, err, err2)
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+	assert.Equal(t, "boom", pe.Value)
+}
+
+func TestErrSizedGroupTermOnErr(t *testing.T) {
+	errFirst := errors.New("first")
+
+	for _, opts := range [][]GroupOption{{TermOnErr}, {TermOnErr, Preemptive}} {
+		g := NewErrSizedGroup(1, opts...)
+		started, release := make(chan struct{}), make(chan struct{})
+		g.Go(func(ctx context.Context) error {
+			close(started)
+			<-release
+			return errFirst
+		})
+		<-started
+
+		// 第一个任务占着信号量，后面的任务在信号量上等待（Preemptive 时 Go 阻塞），出错之后都被跳过
+		var ran atomic.Int32
+		spawned := make(chan struct{})
+		go func() {
+			defer close(spawned)
+			for range 9 {
+				g.Go(func(ctx context.Context) error {
+					ran.Add(1)
+					return nil
+				})
+			}
+		}()
+		close(release)
+		<-spawned
+
+		err := g.Wait()
+		// 只有第一个错误，跳过的任务没有错误
+		assert.Equal(t, errFirst.Error(), err.Error())
+		assert.Equal(t, int32(0), ran.Load())
+		assert.Equal(t, int64(9), g.Skipped())
+
+		// 终止之后的 Go 直接跳过
+		g.Go(func(ctx context.Context) error { return nil })
+		assert.Equal(t, int64(10), g.Skipped())
+	}
+}
+
+func TestErrSizedGroupContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 外部 ctx 被取消，跳过的任务导致 Wait 返回 ctx 的错误
+	g := NewErrSizedGroup(2, Context(ctx))
+	g.Go(func(ctx context.Context) error { return nil })
+	assert.ErrorIs(t, g.Wait(), context.Canceled)
+	assert.Equal(t, int64(1), g.Skipped())
+
+	// 任务拿到的 ctx 在 TermOnErr 终止时被取消
+	g = NewErrSizedGroup(2, TermOnErr)
+	release := make(chan struct{})
+	errs := make(chan error, 1)
+	g.Go(func(ctx context.Context) error {
+		<-release
+		return errors.New("fail")
+	})
+	g.Go(func(ctx context.Context) error {
+		close(release)
+		<-ctx.Done()
+		errs <- ctx.Err()
+		return nil
+	})
+	g.Wait()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
@@ -4,19 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/go-pkgz/syncs"
 	"sync/atomic"
 	"time"
 )
 
 // 通过信号量控制并发的 goroutine 数量，或者不控制 goroutine 的数量，而是控制子任务并发执行的数量
+// 以前用的是 go-pkgz/syncs，现在换成了自己实现的 SizedGroup 和 ErrSizedGroup，用法一样
 
 func SizeGroupDemo() {
 	// 设置 goroutine 的数量为 10
-	swg := syncs.NewSizedGroup(10) // 默认处理方式
-	//swg := syncs.NewSizedGroup(10, syncs.Preemptive) // 设置锁定模式，防止生成等待 goroutine 。可能导致 Go 调用阻塞
-	//swg := syncs.NewSizedGroup(10, syncs.TermOnErr)  // 第一个错误发生后，不再创建新的 goroutine
-	//swg := syncs.NewSizedGroup(10, syncs.Discard)    // 信号量满了之后，不再创建新的 goroutine
+	swg := NewSizedGroup(10) // 默认处理方式
+	//swg := NewSizedGroup(10, Preemptive) // 设置锁定模式，防止生成等待 goroutine 。可能导致 Go 调用阻塞
+	//swg := NewSizedGroup(10, TermOnErr)  // 第一个错误发生后，不再创建新的 goroutine
+	//swg := NewSizedGroup(10, Discard)    // 信号量满了之后，不再创建新的 goroutine
 
 	var c uint32
 
@@ -35,14 +35,14 @@ func SizeGroupDemo() {
 
 func SizeGroupErrDemo() {
 	// 设置了 TermOnErr ，子任务出现第一个 error 时会撤销 Context，后面的 Go 调用会直接返回
-	// Wait 调用者会得到这个错误
-	swg := syncs.NewErrSizedGroup(10, syncs.TermOnErr)
+	// Wait 调用者会得到这个错误，Skipped 返回被跳过的任务数量
+	swg := NewErrSizedGroup(10, TermOnErr)
 
 	var c uint32
 
 	for i := 0; i < 1000; i++ {
 		i := i
-		swg.Go(func() error {
+		swg.Go(func(ctx context.Context) error {
 			if i == 7 {
 				return errors.New("错了")
 			}
@@ -56,5 +56,5 @@ func SizeGroupErrDemo() {
 	if err != nil {
 		fmt.Println(err)
 	}
-	fmt.Println(c)
+	fmt.Println(c, swg.Skipped())
 }
@@ -0,0 +1,187 @@
+package waitgroup
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sync.WaitGroup 的 Wait 不能超时也不能取消，等待的时候也不知道还剩几个、是哪几个任务没完成
+// WaitGroup 兼容 sync.WaitGroup 的 Add/Done/Wait，另外提供：
+//   - Go 自动 Add 和 Done
+//   - WaitContext、WaitTimeout 等待超时或者被取消时返回错误，但不会终止正在执行的任务
+//   - Count 返回当前的计数，DoneChan 返回计数为 0 时关闭的 channel，可以用在 select 中
+//   - Debug 为 true 时记录 Go 启动的每个任务，等待超时时在 *PendingError 中列出还没完成的任务
+//
+// 零值可用
+
+// PendingError 调试模式下等待超时或者被取消时返回的错误，列出还没完成的任务
+type PendingError struct {
+	Err       error    // ctx.Err()
+	Pending   []string // 还没完成的任务名，按启动的顺序，没有名字的任务是调用 Go 的位置
+	Untracked int      // 用 Add 增加、不知道名字的计数
+}
+
+func (e *PendingError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "waitgroup: %v, %d pending", e.Err, len(e.Pending)+e.Untracked)
+	if len(e.Pending) > 0 {
+		fmt.Fprintf(&b, ": %s", strings.Join(e.Pending, ", "))
+	}
+	if e.Untracked > 0 {
+		fmt.Fprintf(&b, " (%d untracked)", e.Untracked)
+	}
+	return b.String()
+}
+
+// Unwrap 返回 ctx.Err()
+func (e *PendingError) Unwrap() error {
+	return e.Err
+}
+
+// closedChan 计数为 0 时 DoneChan 返回的 channel
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// WaitGroup 支持超时和取消的 WaitGroup
+type WaitGroup struct {
+	// Debug 为 true 时记录 Go 启动的任务，需要在使用前设置
+	Debug bool
+
+	mu      sync.Mutex
+	count   int
+	done    chan struct{} // 计数变为 0 时关闭，需要时才创建
+	nextID  uint64
+	pending map[uint64]string
+}
+
+// Add 和 sync.WaitGroup.Add 一样，计数变为负数时 panic
+func (wg *WaitGroup) Add(delta int) {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	wg.addLocked(delta)
+}
+
+// addLocked 修改计数，调用时需要持有 wg.mu
+// 计数会变成负数时先 panic 再修改，recover 之后 WaitGroup 仍然可用
+func (wg *WaitGroup) addLocked(delta int) {
+	if wg.count+delta < 0 {
+		panic("waitgroup: negative WaitGroup counter")
+	}
+	wg.count += delta
+	if wg.count == 0 && wg.done != nil {
+		close(wg.done)
+		wg.done = nil
+	}
+}
+
+// Done 计数减 1
+func (wg *WaitGroup) Done() {
+	wg.Add(-1)
+}
+
+// Go 在新的 goroutine 中执行 f，执行完成后计数减 1
+func (wg *WaitGroup) Go(f func()) {
+	name := ""
+	if wg.Debug {
+		if _, file, line, ok := runtime.Caller(1); ok {
+			name = fmt.Sprintf("%s:%d", file, line)
+		}
+	}
+	wg.GoNamed(name, f)
+}
+
+// GoNamed 和 Go 一样，调试模式下用 name 报告还没完成的任务
+func (wg *WaitGroup) GoNamed(name string, f func()) {
+	wg.mu.Lock()
+	wg.count++
+	id := wg.nextID
+	wg.nextID++
+	if wg.Debug {
+		if wg.pending == nil {
+			wg.pending = make(map[uint64]string)
+		}
+		wg.pending[id] = name
+	}
+	wg.mu.Unlock()
+
+	go func() {
+		defer func() {
+			wg.mu.Lock()
+			delete(wg.pending, id)
+			wg.addLocked(-1)
+			wg.mu.Unlock()
+		}()
+		f()
+	}()
+}
+
+// Count 返回当前的计数
+func (wg *WaitGroup) Count() int {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	return wg.count
+}
+
+// DoneChan 返回一个 channel，计数为 0 时关闭
+// 计数从 0 增加之后需要重新调用 DoneChan
+func (wg *WaitGroup) DoneChan() <-chan struct{} {
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	if wg.count == 0 {
+		return closedChan
+	}
+	if wg.done == nil {
+		wg.done = make(chan struct{})
+	}
+	return wg.done
+}
+
+// Wait 阻塞直到计数为 0
+func (wg *WaitGroup) Wait() {
+	<-wg.DoneChan()
+}
+
+// WaitContext 阻塞直到计数为 0 或者 ctx 被取消，被取消时返回 ctx.Err()，调试模式下返回 *PendingError
+func (wg *WaitGroup) WaitContext(ctx context.Context) error {
+	select {
+	case <-wg.DoneChan():
+		return nil
+	case <-ctx.Done():
+	}
+
+	wg.mu.Lock()
+	defer wg.mu.Unlock()
+	if wg.count == 0 {
+		// 取消的同时完成了
+		return nil
+	}
+	if !wg.Debug {
+		return ctx.Err()
+	}
+	// 按启动的顺序列出
+	ids := make([]uint64, 0, len(wg.pending))
+	for id := range wg.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	pending := make([]string, len(ids))
+	for i, id := range ids {
+		pending[i] = wg.pending[id]
+	}
+	return &PendingError{Err: ctx.Err(), Pending: pending, Untracked: wg.count - len(wg.pending)}
+}
+
+// WaitTimeout 最多等待 d，超时返回 context.DeadlineExceeded，调试模式下返回 *PendingError
+func (wg *WaitGroup) WaitTimeout(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return wg.WaitContext(ctx)
+}
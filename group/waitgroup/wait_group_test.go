@@ -0,0 +1,110 @@
+package waitgroup
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitGroup(t *testing.T) {
+	var wg WaitGroup
+	// 零值直接 Wait 不阻塞
+	wg.Wait()
+	assert.NoError(t, wg.WaitTimeout(time.Millisecond))
+
+	var n atomic.Int32
+	for range 10 {
+		wg.Go(func() {
+			time.Sleep(time.Millisecond)
+			n.Add(1)
+		})
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.Add(1)
+	}()
+	wg.Wait()
+	assert.Equal(t, int32(11), n.Load())
+	assert.Equal(t, 0, wg.Count())
+
+	assert.Panics(t, func() { wg.Done() })
+	// panic 之后计数没有被修改，WaitGroup 仍然可用
+	assert.Equal(t, 0, wg.Count())
+	wg.Wait()
+	wg.Go(func() {})
+	wg.Wait()
+}
+
+func TestWaitGroupContext(t *testing.T) {
+	var wg WaitGroup
+	release := make(chan struct{})
+	for range 3 {
+		wg.Go(func() { <-release })
+	}
+	assert.Equal(t, 3, wg.Count())
+
+	// 超时返回错误，不影响正在执行的任务
+	err := wg.WaitTimeout(10 * time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, wg.WaitContext(ctx), context.Canceled)
+	assert.Equal(t, 3, wg.Count())
+
+	close(release)
+	assert.NoError(t, wg.WaitContext(context.Background()))
+}
+
+func TestWaitGroupDoneChan(t *testing.T) {
+	var wg WaitGroup
+	select {
+	case <-wg.DoneChan():
+	default:
+		t.Fatal("DoneChan of an idle WaitGroup should be closed")
+	}
+
+	release := make(chan struct{})
+	wg.Go(func() { <-release })
+	done := wg.DoneChan()
+	select {
+	case <-done:
+		t.Fatal("DoneChan should block while tasks are running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	assert.Equal(t, 0, wg.Count())
+}
+
+func TestWaitGroupDebug(t *testing.T) {
+	wg := WaitGroup{Debug: true}
+	release := make(chan struct{})
+	wg.GoNamed("fetch baidu", func() { <-release })
+	wg.GoNamed("fetch bing", func() { <-release })
+	wg.GoNamed("fetch google", func() {})
+	wg.Go(func() { <-release })
+	wg.Add(1)
+
+	// 超时时报告还没完成的任务
+	assert.Eventually(t, func() bool { return wg.Count() == 4 }, time.Second, time.Millisecond)
+	err := wg.WaitTimeout(10 * time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var pe *PendingError
+	assert.ErrorAs(t, err, &pe)
+	assert.Len(t, pe.Pending, 3)
+	assert.Equal(t, "fetch baidu", pe.Pending[0])
+	assert.Equal(t, "fetch bing", pe.Pending[1])
+	// 没有名字的任务用调用 Go 的位置
+	assert.Contains(t, pe.Pending[2], "wait_group_test.go:")
+	assert.Equal(t, 1, pe.Untracked)
+	assert.Contains(t, err.Error(), "4 pending: fetch baidu, fetch bing")
+
+	close(release)
+	wg.Done()
+	wg.Wait()
+}
@@ -0,0 +1,33 @@
+package panics
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+)
+
+// Error panic 时的值和调用栈，recover 之后转换成 error 或者在另一个 goroutine 中重新 panic
+type Error struct {
+	Value any
+	Stack []byte
+}
+
+func (p *Error) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap panic 的值是 error 时返回这个 error
+func (p *Error) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// New 用 recover 得到的值创建 *Error，需要在 recover 的 defer 中调用，这样调用栈才是 panic 的位置
+func New(v any) *Error {
+	stack := debug.Stack()
+	// 去掉第一行 "goroutine N [running]:"，重新 panic 时会有新的一行
+	if line := bytes.IndexByte(stack, '\n'); line >= 0 {
+		stack = stack[line+1:]
+	}
+	return &Error{Value: v, Stack: stack}
+}
@@ -0,0 +1,31 @@
+package panics
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recovered(f func()) (pe *Error) {
+	defer func() {
+		pe = New(recover())
+	}()
+	f()
+	return nil
+}
+
+func TestError(t *testing.T) {
+	pe := recovered(func() { panic(io.EOF) })
+	assert.Equal(t, io.EOF, pe.Value)
+	assert.ErrorIs(t, pe, io.EOF)
+	assert.True(t, strings.HasPrefix(pe.Error(), "panic: EOF"))
+	// 调用栈从 panic 的位置开始，没有 "goroutine N [running]:"
+	assert.False(t, strings.HasPrefix(string(pe.Stack), "goroutine "))
+	assert.Contains(t, string(pe.Stack), "panics_test.go")
+
+	pe = recovered(func() { panic("boom") })
+	assert.Nil(t, errors.Unwrap(pe))
+}
@@ -0,0 +1,126 @@
+package once
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// 按 key 懒加载，每个 key 只初始化一次，比如每个租户一个数据库连接、每个下游一个 client
+// 同一个 key 的并发调用只有一个执行初始化函数，其他的等待它的结果，不同 key 之间互不影响
+// 和 OnceErr 一样，默认初始化失败不会被缓存，下次调用重新执行
+
+// ErrClosed KeyedOnce 已经关闭
+var ErrClosed = errors.New("once: closed")
+
+// KeyedOnce 按 key 初始化一次的注册表，零值可以直接使用
+type KeyedOnce[K comparable, V any] struct {
+	// CacheErrors 为 true 时初始化失败也会被缓存，直到 Evict，需要在使用前设置
+	CacheErrors bool
+
+	mu      sync.Mutex
+	entries map[K]*onceEntry[V]
+	closed  bool
+}
+
+// onceEntry 是一个 key 的初始化结果
+type onceEntry[V any] struct {
+	done  chan struct{} // 初始化结束时关闭
+	value V
+	err   error
+}
+
+// GetOrInit 返回 key 对应的值，还没有初始化就在当前 goroutine 执行 fn
+// fn panic 时在当前 goroutine 继续 panic，等待同一个 key 的其他调用者得到 ErrPanicked
+func (k *KeyedOnce[K, V]) GetOrInit(key K, fn func() (V, error)) (V, error) {
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+		var zero V
+		return zero, ErrClosed
+	}
+	if e, ok := k.entries[key]; ok {
+		k.mu.Unlock()
+		<-e.done
+		return e.value, e.err
+	}
+
+	if k.entries == nil {
+		k.entries = make(map[K]*onceEntry[V])
+	}
+	e := &onceEntry[V]{done: make(chan struct{})}
+	k.entries[key] = e
+	k.mu.Unlock()
+
+	k.init(key, e, fn)
+	return e.value, e.err
+}
+
+// Evict 移除 key，下次 GetOrInit 会重新初始化
+// 正在初始化时等待初始化结束，值实现了 io.Closer 时会被关闭，返回关闭的错误
+func (k *KeyedOnce[K, V]) Evict(key K) error {
+	k.mu.Lock()
+	e, ok := k.entries[key]
+	delete(k.entries, key)
+	k.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return e.close()
+}
+
+// Len 返回已经初始化或正在初始化的 key 的数量
+func (k *KeyedOnce[K, V]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.entries)
+}
+
+// Close 关闭所有实现了 io.Closer 的值，之后 GetOrInit 都返回 ErrClosed
+// 正在初始化的 key 会等待初始化结束再关闭，返回所有关闭错误合并后的错误
+func (k *KeyedOnce[K, V]) Close() error {
+	k.mu.Lock()
+	k.closed = true
+	entries := k.entries
+	k.entries = nil
+	k.mu.Unlock()
+
+	var errs []error
+	for _, e := range entries {
+		errs = append(errs, e.close())
+	}
+	return errors.Join(errs...)
+}
+
+func (k *KeyedOnce[K, V]) init(key K, e *onceEntry[V], fn func() (V, error)) {
+	panicked := true
+	defer func() {
+		if panicked {
+			e.err = ErrPanicked
+		}
+		if e.err != nil && (panicked || !k.CacheErrors) {
+			k.mu.Lock()
+			if k.entries[key] == e {
+				delete(k.entries, key)
+			}
+			k.mu.Unlock()
+		}
+		close(e.done)
+	}()
+
+	e.value, e.err = fn()
+	panicked = false
+}
+
+// close 等待初始化结束，关闭初始化成功的值
+func (e *onceEntry[V]) close() error {
+	<-e.done
+	if e.err != nil {
+		return nil
+	}
+	if c, ok := any(e.value).(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package once
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClient struct {
+	tenant string
+	closed atomic.Bool
+}
+
+func (c *fakeClient) Close() error {
+	if c.closed.Swap(true) {
+		return errors.New("closed twice")
+	}
+	return nil
+}
+
+func TestKeyedOnce(t *testing.T) {
+	var (
+		ko    KeyedOnce[string, *fakeClient]
+		calls sync.Map // tenant -> *atomic.Int32
+		wg    sync.WaitGroup
+	)
+
+	// 每个 key 只初始化一次
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tenant := []string{"a", "b", "c"}[i%3]
+			c, err := ko.GetOrInit(tenant, func() (*fakeClient, error) {
+				n, _ := calls.LoadOrStore(tenant, new(atomic.Int32))
+				n.(*atomic.Int32).Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return &fakeClient{tenant: tenant}, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tenant, c.tenant)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, ko.Len())
+	calls.Range(func(_, n any) bool {
+		assert.Equal(t, int32(1), n.(*atomic.Int32).Load())
+		return true
+	})
+}
+
+func TestKeyedOnceErrors(t *testing.T) {
+	errInit := errors.New("init failed")
+	fail := func() (int, error) { return 0, errInit }
+	ok := func() (int, error) { return 1, nil }
+
+	// 默认不缓存错误
+	var ko KeyedOnce[string, int]
+	_, err := ko.GetOrInit("k", fail)
+	assert.ErrorIs(t, err, errInit)
+	v, err := ko.GetOrInit("k", ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// 缓存错误，直到 Evict
+	ko2 := KeyedOnce[string, int]{CacheErrors: true}
+	_, err = ko2.GetOrInit("k", fail)
+	assert.ErrorIs(t, err, errInit)
+	_, err = ko2.GetOrInit("k", ok)
+	assert.ErrorIs(t, err, errInit)
+	assert.NoError(t, ko2.Evict("k"))
+	v, err = ko2.GetOrInit("k", ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// panic 不会被缓存
+	assert.Panics(t, func() {
+		_, _ = ko2.GetOrInit("p", func() (int, error) { panic("boom") })
+	})
+	v, err = ko2.GetOrInit("p", ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestKeyedOnceEvictAndClose(t *testing.T) {
+	var ko KeyedOnce[string, *fakeClient]
+	newClient := func(tenant string) func() (*fakeClient, error) {
+		return func() (*fakeClient, error) { return &fakeClient{tenant: tenant}, nil }
+	}
+
+	a, _ := ko.GetOrInit("a", newClient("a"))
+	b, _ := ko.GetOrInit("b", newClient("b"))
+
+	// Evict 关闭被移除的值，下次重新初始化
+	assert.NoError(t, ko.Evict("a"))
+	assert.True(t, a.closed.Load())
+	a2, _ := ko.GetOrInit("a", newClient("a"))
+	assert.NotSame(t, a, a2)
+
+	assert.NoError(t, ko.Close())
+	assert.True(t, a2.closed.Load())
+	assert.True(t, b.closed.Load())
+
+	_, err := ko.GetOrInit("a", newClient("a"))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.Equal(t, 0, ko.Len())
+}
@@ -0,0 +1,149 @@
+package once
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// sync.OnceValue 的结果永远不会过期，Demo 里的时间戳算一次就一直用下去
+// LazyValue 第一次使用时才计算，结果缓存 ttl 时间：
+//   - 快过期时（剩余时间不到 refreshAhead）在后台刷新，调用者继续拿到旧值，不用等待（stale-while-revalidate）
+//   - 已经过期就同步刷新，调用者等待新值
+//   - 刷新失败时，只要过期没超过 maxStale 就继续返回旧值，避免下游抖动时把错误传给所有调用者
+//   - 同一时刻最多只有一个刷新在执行，并发的调用者共享这次刷新的结果
+
+// LazyValue 带过期时间、后台刷新的懒加载值
+type LazyValue[T any] struct {
+	fn  func(ctx context.Context) (T, error)
+	ttl time.Duration
+	lazyOptions
+
+	mu       sync.Mutex
+	value    T
+	hasValue bool
+	updated  time.Time    // value 的计算时间
+	refresh  *refreshCall // 正在执行的刷新，没有时为 nil
+}
+
+// refreshCall 是一次刷新
+type refreshCall struct {
+	done chan struct{} // 刷新结束时关闭
+	err  error
+}
+
+// LazyOption LazyValue 的选项
+type LazyOption func(*lazyOptions)
+
+type lazyOptions struct {
+	refreshAhead time.Duration
+	maxStale     time.Duration
+	clock        clock.Clock
+}
+
+// WithRefreshAhead 剩余有效时间不到 d 时在后台刷新
+func WithRefreshAhead(d time.Duration) LazyOption {
+	return func(o *lazyOptions) {
+		o.refreshAhead = d
+	}
+}
+
+// WithMaxStale 刷新失败时，过期不超过 d 的旧值仍然可以返回
+func WithMaxStale(d time.Duration) LazyOption {
+	return func(o *lazyOptions) {
+		o.maxStale = d
+	}
+}
+
+// WithClock 设置使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) LazyOption {
+	return func(o *lazyOptions) {
+		o.clock = c
+	}
+}
+
+// NewLazyValue 创建一个懒加载值，fn 计算出的结果缓存 ttl 时间
+func NewLazyValue[T any](fn func(ctx context.Context) (T, error), ttl time.Duration, opts ...LazyOption) *LazyValue[T] {
+	l := &LazyValue[T]{
+		fn:          fn,
+		ttl:         ttl,
+		lazyOptions: lazyOptions{clock: clock.New()},
+	}
+	for _, opt := range opts {
+		opt(&l.lazyOptions)
+	}
+	return l
+}
+
+// Get 返回缓存的值，没有值或者已经过期时等待刷新
+// ctx 只控制调用者等待的时间，刷新在新的 goroutine 中执行，调用者放弃后刷新会继续完成
+func (l *LazyValue[T]) Get(ctx context.Context) (T, error) {
+	l.mu.Lock()
+	if l.hasValue {
+		age := l.clock.Since(l.updated)
+		if age < l.ttl {
+			// 快过期了，后台刷新，先返回旧值
+			if age >= l.ttl-l.refreshAhead {
+				l.startRefresh(ctx)
+			}
+			v := l.value
+			l.mu.Unlock()
+			return v, nil
+		}
+	}
+	c := l.startRefresh(ctx)
+	l.mu.Unlock()
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c.err == nil {
+		return l.value, nil
+	}
+	// 刷新失败，旧值过期不久就继续用
+	if l.hasValue && l.clock.Since(l.updated) < l.ttl+l.maxStale {
+		return l.value, nil
+	}
+	var zero T
+	return zero, c.err
+}
+
+// startRefresh 返回正在执行的刷新，没有就启动一个，调用时需要持有 l.mu
+func (l *LazyValue[T]) startRefresh(ctx context.Context) *refreshCall {
+	if l.refresh != nil {
+		return l.refresh
+	}
+
+	c := &refreshCall{done: make(chan struct{})}
+	l.refresh = c
+	// 刷新不受调用者取消的影响，但是保留 ctx 里的值（trace id 等）
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		// fn panic 时记成这次刷新的错误，l.refresh 也会清空，之后的调用可以重新刷新
+		var v T
+		err := callRecover(func() (err error) {
+			v, err = l.fn(ctx)
+			return err
+		})
+
+		l.mu.Lock()
+		if err == nil {
+			l.value = v
+			l.hasValue = true
+			l.updated = l.clock.Now()
+		}
+		c.err = err
+		l.refresh = nil
+		l.mu.Unlock()
+		close(c.done)
+	}()
+	return c
+}
@@ -0,0 +1,143 @@
+package once
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazyValueTTL(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	var calls atomic.Int32
+	l := NewLazyValue(func(ctx context.Context) (int32, error) {
+		return calls.Add(1), nil
+	}, time.Minute, WithClock(c))
+
+	v, err := l.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	// 没过期一直用缓存的值
+	c.Advance(59 * time.Second)
+	v, _ = l.Get(context.Background())
+	assert.Equal(t, int32(1), v)
+
+	// 过期了同步刷新
+	c.Advance(time.Second)
+	v, _ = l.Get(context.Background())
+	assert.Equal(t, int32(2), v)
+}
+
+func TestLazyValueRefreshAhead(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	var calls atomic.Int32
+	release := make(chan struct{})
+	l := NewLazyValue(func(ctx context.Context) (int32, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			<-release
+		}
+		return n, nil
+	}, time.Minute, WithRefreshAhead(10*time.Second), WithClock(c))
+
+	v, _ := l.Get(context.Background())
+	assert.Equal(t, int32(1), v)
+
+	// 快过期了，后台刷新，调用者不用等，并发的调用只触发一次刷新
+	c.Advance(55 * time.Second)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Get(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, int32(1), v)
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		v, _ := l.Get(context.Background())
+		return v == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestLazyValueMaxStale(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	errDown := errors.New("downstream unavailable")
+	var fail atomic.Bool
+	l := NewLazyValue(func(ctx context.Context) (string, error) {
+		if fail.Load() {
+			return "", errDown
+		}
+		return "good", nil
+	}, time.Minute, WithMaxStale(time.Minute), WithClock(c))
+
+	// 第一次就失败，没有旧值可用
+	fail.Store(true)
+	_, err := l.Get(context.Background())
+	assert.ErrorIs(t, err, errDown)
+
+	fail.Store(false)
+	v, _ := l.Get(context.Background())
+	assert.Equal(t, "good", v)
+
+	// 过期后刷新失败，过期不超过 maxStale 时返回旧值
+	fail.Store(true)
+	c.Advance(90 * time.Second)
+	v, err = l.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "good", v)
+
+	// 超过了 maxStale，返回错误
+	c.Advance(30 * time.Second)
+	_, err = l.Get(context.Background())
+	assert.ErrorIs(t, err, errDown)
+}
+
+func TestLazyValueCancel(t *testing.T) {
+	release := make(chan struct{})
+	l := NewLazyValue(func(ctx context.Context) (int, error) {
+		<-release
+		return 42, nil
+	}, time.Minute)
+
+	// 调用者放弃等待，刷新继续执行
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := l.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	v, err := l.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
+
+func TestLazyValuePanic(t *testing.T) {
+	var calls atomic.Int32
+	l := NewLazyValue(func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return 42, nil
+	}, time.Minute)
+
+	// fn panic 时不会让进程崩溃，调用者得到 ErrPanicked
+	_, err := l.Get(context.Background())
+	assert.ErrorIs(t, err, ErrPanicked)
+
+	// 之后的调用重新刷新，不会一直等待那次 panic 的刷新
+	v, err := l.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, v)
+}
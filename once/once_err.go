@@ -0,0 +1,136 @@
+package once
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// sync.Once 不管 f 成功还是失败都只执行一次，初始化失败（比如连接数据库超时）之后就再也没机会重试了
+// OnceErr 只有 f 返回 nil 才算初始化完成，失败了下一次调用会重新执行 f
+// 同一时刻只有一个 goroutine 在执行 f，其他调用者等待它的结果，和 singleflight 的思路一样
+
+// ErrPanicked 初始化函数 panic 时，等待同一次初始化的其他调用者得到这个错误
+// 在后台 goroutine 中执行的函数 panic 时，所有调用者得到包装了 ErrPanicked 和 panic 值的错误
+var ErrPanicked = errors.New("once: initializer panicked")
+
+// OnceErr 可以重试、可以重置的 Once
+type OnceErr struct {
+	done atomic.Bool // 快速路径，初始化完成后不用再加锁
+	mu   sync.Mutex
+	call *onceCall // 正在执行的初始化，没有时为 nil
+}
+
+// onceCall 是一次初始化
+type onceCall struct {
+	done chan struct{} // 初始化结束时关闭
+	err  error
+}
+
+// Do 执行初始化函数 f，直到 f 成功返回 nil 为止
+// 已经初始化成功直接返回 nil；正在初始化时等待这次初始化的结果；否则在当前 goroutine 执行 f
+// f panic 时在当前 goroutine 继续 panic，等待这次初始化的其他调用者得到 ErrPanicked，下次调用重新执行
+func (o *OnceErr) Do(f func() error) error {
+	if o.done.Load() {
+		return nil
+	}
+
+	c, leader := o.begin()
+	if c == nil {
+		return nil
+	}
+	if leader {
+		o.run(c, f)
+	}
+	<-c.done
+	return c.err
+}
+
+// DoContext 和 Do 一样，但是调用者可以通过 ctx 放弃等待
+// f 在新的 goroutine 中执行，调用者放弃后 f 会继续执行完，成功的话仍然算初始化完成
+// f panic 时不会让进程崩溃，所有调用者得到包装了 ErrPanicked 的错误，下次调用重新执行
+func (o *OnceErr) DoContext(ctx context.Context, f func() error) error {
+	if o.done.Load() {
+		return nil
+	}
+
+	c, leader := o.begin()
+	if c == nil {
+		return nil
+	}
+	if leader {
+		go o.run(c, func() error { return callRecover(f) })
+	}
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done 是否已经初始化成功
+func (o *OnceErr) Done() bool {
+	return o.done.Load()
+}
+
+// Reset 重置为没有初始化的状态，下次调用 Do 会重新执行初始化函数
+// 用于测试，或者连接断开后需要重新初始化的场景；正在执行的初始化不受影响
+func (o *OnceErr) Reset() {
+	o.mu.Lock()
+	o.done.Store(false)
+	o.mu.Unlock()
+}
+
+// begin 返回正在执行的初始化，没有就新建一个，leader 表示需要调用者执行初始化
+// 已经初始化成功时返回 nil
+func (o *OnceErr) begin() (c *onceCall, leader bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.done.Load() {
+		return nil, false
+	}
+	if o.call != nil {
+		return o.call, false
+	}
+	o.call = &onceCall{done: make(chan struct{})}
+	return o.call, true
+}
+
+func (o *OnceErr) run(c *onceCall, f func() error) {
+	panicked := true
+	defer func() {
+		if panicked {
+			c.err = ErrPanicked
+		}
+
+		o.mu.Lock()
+		if c.err == nil {
+			o.done.Store(true)
+		}
+		o.call = nil
+		o.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.err = f()
+	panicked = false
+}
+
+// callRecover 执行 f，把 panic 转换成包装了 ErrPanicked 的错误
+func callRecover(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			if e, ok := v.(error); ok {
+				err = fmt.Errorf("%w: %w", ErrPanicked, e)
+			} else {
+				err = fmt.Errorf("%w: %v", ErrPanicked, v)
+			}
+		}
+	}()
+	return f()
+}
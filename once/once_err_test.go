@@ -0,0 +1,123 @@
+package once
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnceErrRetry(t *testing.T) {
+	var (
+		o     OnceErr
+		calls int
+	)
+	errConn := errors.New("connect timeout")
+	f := func() error {
+		calls++
+		if calls < 3 {
+			return errConn
+		}
+		return nil
+	}
+
+	// 失败了下次调用会重试，成功之后不再执行
+	assert.ErrorIs(t, o.Do(f), errConn)
+	assert.ErrorIs(t, o.Do(f), errConn)
+	assert.False(t, o.Done())
+	assert.NoError(t, o.Do(f))
+	assert.NoError(t, o.Do(f))
+	assert.True(t, o.Done())
+	assert.Equal(t, 3, calls)
+
+	// 重置后重新初始化
+	o.Reset()
+	assert.False(t, o.Done())
+	assert.NoError(t, o.Do(f))
+	assert.Equal(t, 4, calls)
+}
+
+func TestOnceErrConcurrent(t *testing.T) {
+	var (
+		o     OnceErr
+		calls atomic.Int32
+		wg    sync.WaitGroup
+	)
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, o.Do(func() error {
+				calls.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			}))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestOnceErrPanic(t *testing.T) {
+	var o OnceErr
+	assert.Panics(t, func() {
+		_ = o.Do(func() error { panic("虾米诺手") })
+	})
+
+	// panic 不算初始化成功
+	assert.False(t, o.Done())
+	assert.NoError(t, o.Do(func() error { return nil }))
+}
+
+func TestOnceErrDoContextPanic(t *testing.T) {
+	var o OnceErr
+	// 后台执行的 f panic 时不会让进程崩溃，调用者得到 ErrPanicked
+	err := o.DoContext(context.Background(), func() error { panic("虾米诺手") })
+	assert.ErrorIs(t, err, ErrPanicked)
+	assert.ErrorContains(t, err, "虾米诺手")
+
+	errBoom := errors.New("boom")
+	err = o.DoContext(context.Background(), func() error { panic(errBoom) })
+	assert.ErrorIs(t, err, ErrPanicked)
+	assert.ErrorIs(t, err, errBoom)
+
+	// panic 不算初始化成功
+	assert.False(t, o.Done())
+	assert.NoError(t, o.DoContext(context.Background(), func() error { return nil }))
+	assert.True(t, o.Done())
+}
+
+func TestOnceErrDoContext(t *testing.T) {
+	var o OnceErr
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	f := func() error {
+		defer close(finished)
+		<-release
+		return nil
+	}
+
+	// 等待者放弃了，初始化函数继续执行
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, o.DoContext(ctx, f), context.DeadlineExceeded)
+	assert.False(t, o.Done())
+
+	// 后来的调用者等待同一次初始化，不会再执行一次
+	done := make(chan error)
+	go func() {
+		done <- o.DoContext(context.Background(), func() error {
+			t.Error("initializer should not run twice")
+			return nil
+		})
+	}()
+
+	close(release)
+	<-finished
+	assert.NoError(t, <-done)
+	assert.True(t, o.Done())
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// Keyed 按 key（用户、IP、API Key 等）分别限流，每个 key 一个限流器
+// key 第一次出现时用 newLimiter 创建限流器，所以不同的 key 可以有不同的配置
+// 超过 idle 时间没有使用的 key 会被清理掉，idle 应该不小于限流器恢复到初始状态的时间，
+// 比如令牌桶重新装满的时间，否则清理后重新创建的限流器会多放过一些请求
+type Keyed[K comparable, L Limiter] struct {
+	clock      clock.Clock
+	newLimiter func(key K) L
+	idle       time.Duration
+
+	mu        sync.Mutex
+	limiters  map[K]*keyedLimiter[L]
+	lastSweep time.Time
+}
+
+type keyedLimiter[L Limiter] struct {
+	limiter  L
+	lastUsed time.Time
+}
+
+// NewKeyed 创建一个按 key 限流的限流器，超过 idle 时间没有使用的 key 会被清理
+func NewKeyed[K comparable, L Limiter](newLimiter func(key K) L, idle time.Duration, opts ...Option) *Keyed[K, L] {
+	o := newOptions(opts)
+	return &Keyed[K, L]{
+		clock:      o.clock,
+		newLimiter: newLimiter,
+		idle:       idle,
+		limiters:   make(map[K]*keyedLimiter[L]),
+		lastSweep:  o.clock.Now(),
+	}
+}
+
+// Allow key 的请求能否放过
+func (k *Keyed[K, L]) Allow(key K) bool {
+	return k.Get(key).Allow()
+}
+
+// Get 返回 key 对应的限流器，需要 AllowN、Wait 等方法时使用
+func (k *Keyed[K, L]) Get(key K) L {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.clock.Now()
+	// 顺便清理一下，每个 idle 周期最多清理一次，均摊下来每次调用是 O(1)
+	if now.Sub(k.lastSweep) >= k.idle {
+		k.sweep(now)
+	}
+
+	kl, ok := k.limiters[key]
+	if !ok {
+		kl = &keyedLimiter[L]{limiter: k.newLimiter(key)}
+		k.limiters[key] = kl
+	}
+	kl.lastUsed = now
+	return kl.limiter
+}
+
+// Len 返回当前的 key 数量
+func (k *Keyed[K, L]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// Sweep 立即清理超过 idle 时间没有使用的 key
+func (k *Keyed[K, L]) Sweep() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sweep(k.clock.Now())
+}
+
+func (k *Keyed[K, L]) sweep(now time.Time) {
+	for key, kl := range k.limiters {
+		if now.Sub(kl.lastUsed) >= k.idle {
+			delete(k.limiters, key)
+		}
+	}
+	k.lastSweep = now
+}
@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyed(t *testing.T) {
+	c := clock.NewFake(epoch)
+	k := NewKeyed(func(user string) *TokenBucket {
+		if user == "vip" {
+			return NewTokenBucket(1, 3, WithClock(c))
+		}
+		return NewTokenBucket(1, 1, WithClock(c))
+	}, time.Minute, WithClock(c))
+
+	// 每个 key 单独限流，配置也可以不同
+	assert.True(t, k.Allow("alice"))
+	assert.False(t, k.Allow("alice"))
+	assert.True(t, k.Allow("bob"))
+	assert.True(t, k.Get("vip").AllowN(3))
+	assert.Equal(t, 3, k.Len())
+
+	// bob 一直在用，不会被清理
+	c.Advance(40 * time.Second)
+	assert.True(t, k.Allow("bob"))
+	c.Advance(20 * time.Second)
+	k.Sweep()
+	assert.Equal(t, 1, k.Len())
+
+	// 调用时也会顺便清理
+	c.Advance(time.Minute)
+	assert.True(t, k.Allow("alice"))
+	assert.Equal(t, 1, k.Len())
+}
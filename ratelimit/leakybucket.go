@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// LeakyBucket 漏桶
+// 请求先进桶排队，桶底以固定的间隔漏出请求，桶满了新来的请求就被丢弃
+// 和令牌桶不同，漏桶不允许突发：不管请求来得多集中，流出的请求之间总是隔着 1/rate 秒，起到平滑（整形）的作用
+// 同样不需要真的维护一个队列，只需要记录下一个请求可以流出的时间
+type LeakyBucket struct {
+	clock    clock.Clock
+	interval time.Duration // 两个请求之间的间隔
+	capacity int           // 桶里最多排队的请求数
+
+	mu   sync.Mutex
+	next time.Time // 下一个请求可以流出的时间
+}
+
+// NewLeakyBucket 创建一个漏桶，每秒漏出 rate 个请求，最多排队 capacity 个请求
+func NewLeakyBucket(rate float64, capacity int, opts ...Option) *LeakyBucket {
+	o := newOptions(opts)
+	return &LeakyBucket{
+		clock:    o.clock,
+		interval: time.Duration(float64(time.Second) / rate),
+		capacity: capacity,
+	}
+}
+
+// Allow 请求能否不排队直接流出，不会阻塞
+func (lb *LeakyBucket) Allow() bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := lb.clock.Now()
+	if lb.next.After(now) {
+		return false
+	}
+	lb.next = now.Add(lb.interval)
+	return true
+}
+
+// Wait 进桶排队，阻塞直到轮到这个请求流出
+// 桶满了立即返回 ErrLimited，ctx 被取消时返回 ctx.Err()
+func (lb *LeakyBucket) Wait(ctx context.Context) error {
+	lb.mu.Lock()
+	now := lb.clock.Now()
+	at := lb.next
+	if at.Before(now) {
+		at = now
+	}
+	wait := at.Sub(now)
+	if wait > time.Duration(lb.capacity)*lb.interval {
+		lb.mu.Unlock()
+		return ErrLimited
+	}
+	lb.next = at.Add(lb.interval)
+	lb.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	t := lb.clock.NewTimer(wait)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		lb.mu.Lock()
+		// 自己排在队尾时把位置让出来，排在中间就没办法了，后面的请求不会提前
+		if lb.next.Equal(at.Add(lb.interval)) {
+			lb.next = at
+		}
+		lb.mu.Unlock()
+		return ctx.Err()
+	}
+}
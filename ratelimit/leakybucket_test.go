@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeakyBucketAllow(t *testing.T) {
+	c := clock.NewFake(epoch)
+	lb := NewLeakyBucket(10, 0, WithClock(c))
+
+	// 不允许突发，两个请求之间至少隔 100ms
+	assert.True(t, lb.Allow())
+	assert.False(t, lb.Allow())
+	c.Advance(50 * time.Millisecond)
+	assert.False(t, lb.Allow())
+	c.Advance(50 * time.Millisecond)
+	assert.True(t, lb.Allow())
+}
+
+func TestLeakyBucketWait(t *testing.T) {
+	c := clock.NewFake(epoch)
+	lb := NewLeakyBucket(10, 2, WithClock(c))
+
+	assert.NoError(t, lb.Wait(context.Background()))
+
+	// 两个请求排队，依次在 100ms、200ms 时流出
+	done := make(chan time.Time, 2)
+	for range 2 {
+		go func() {
+			assert.NoError(t, lb.Wait(context.Background()))
+			done <- c.Now()
+		}()
+	}
+	c.BlockUntil(2)
+
+	// 桶满了
+	assert.ErrorIs(t, lb.Wait(context.Background()), ErrLimited)
+
+	c.Advance(100 * time.Millisecond)
+	assert.Equal(t, epoch.Add(100*time.Millisecond), <-done)
+	c.Advance(100 * time.Millisecond)
+	assert.Equal(t, epoch.Add(200*time.Millisecond), <-done)
+}
+
+func TestLeakyBucketCancel(t *testing.T) {
+	c := clock.NewFake(epoch)
+	lb := NewLeakyBucket(10, 1, WithClock(c))
+	assert.True(t, lb.Allow())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- lb.Wait(ctx) }()
+	c.BlockUntil(1)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// 排在队尾的请求放弃后，位置让给后来的请求
+	c.Advance(100 * time.Millisecond)
+	assert.True(t, lb.Allow())
+}
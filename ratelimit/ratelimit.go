@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"errors"
+
+	"concurrence/clock"
+)
+
+// semaphore 限制的是同时在处理的请求数（并发），ratelimit 限制的是单位时间内的请求数（速率）
+// 令牌桶：按固定速率往桶里放令牌，桶满了就不放了，允许一定程度的突发
+// 漏桶：请求按固定的间隔流出，把突发的请求整形成均匀的请求
+// 固定窗口计数：每个时间窗口一个计数器，简单但是窗口边界处可能放过两倍的请求
+// 滑动窗口计数：用上一个窗口的计数按比例估算，缓解窗口边界的问题
+
+// ErrLimited 请求超过了限制
+var ErrLimited = errors.New("ratelimit: rate limit exceeded")
+
+// Limiter 是所有限流器都实现的接口
+type Limiter interface {
+	Allow() bool
+}
+
+// Option 限流器的选项
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock 设置限流器使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
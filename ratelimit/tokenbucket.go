@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// TokenBucket 令牌桶
+// 以 rate 个每秒的速率往桶里放令牌，桶里最多 burst 个令牌，每个请求拿走一个令牌，拿不到就限流
+// 桶满时可以一下子放过 burst 个请求，所以令牌桶允许突发流量，长期来看速率不超过 rate
+// 和 golang.org/x/time/rate 一样，不是真的有 goroutine 往桶里放令牌，而是在请求时根据经过的时间计算
+type TokenBucket struct {
+	clock clock.Clock
+	rate  float64 // 每秒放入的令牌数
+	burst int     // 桶的容量
+
+	mu        sync.Mutex
+	tokens    float64   // 桶里的令牌数，有预约时可能是负数
+	last      time.Time // 上次计算令牌数的时间
+	lastEvent time.Time // 最后一个预约的令牌可用的时间
+}
+
+// NewTokenBucket 创建一个令牌桶，每秒放入 rate 个令牌，容量为 burst，初始时桶是满的
+func NewTokenBucket(rate float64, burst int, opts ...Option) *TokenBucket {
+	o := newOptions(opts)
+	return &TokenBucket{
+		clock:  o.clock,
+		rate:   rate,
+		burst:  burst,
+		tokens: float64(burst),
+		last:   o.clock.Now(),
+	}
+}
+
+// Allow 等同于 AllowN(1)
+func (tb *TokenBucket) Allow() bool {
+	return tb.AllowN(1)
+}
+
+// AllowN 现在能否拿到 n 个令牌，不会阻塞
+func (tb *TokenBucket) AllowN(n int) bool {
+	return tb.reserveN(tb.clock.Now(), n, 0).ok
+}
+
+// Wait 等同于 WaitN(ctx, 1)
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return tb.WaitN(ctx, 1)
+}
+
+// WaitN 阻塞直到拿到 n 个令牌
+// n 超过桶的容量，或者 ctx 的截止时间之前拿不到令牌时，立即返回 ErrLimited
+func (tb *TokenBucket) WaitN(ctx context.Context, n int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	now := tb.clock.Now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+
+	r := tb.reserveN(now, n, maxWait)
+	if !r.ok {
+		return ErrLimited
+	}
+
+	delay := r.timeToAct.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	t := tb.clock.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		// 没等到就放弃了，把预约的令牌还回去
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// Reserve 等同于 ReserveN(1)
+func (tb *TokenBucket) Reserve() *Reservation {
+	return tb.ReserveN(1)
+}
+
+// ReserveN 预约 n 个令牌，不会阻塞，调用者自己等到 Delay 之后再执行
+// n 超过桶的容量时预约失败，OK 返回 false
+func (tb *TokenBucket) ReserveN(n int) *Reservation {
+	return tb.reserveN(tb.clock.Now(), n, time.Duration(math.MaxInt64))
+}
+
+// reserveN 预约 n 个令牌，需要等待的时间超过 maxWait 时预约失败
+func (tb *TokenBucket) reserveN(now time.Time, n int, maxWait time.Duration) *Reservation {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	if n > tb.burst {
+		return &Reservation{}
+	}
+
+	tb.advance(now)
+
+	tokens := tb.tokens - float64(n)
+	var wait time.Duration
+	if tokens < 0 {
+		// 令牌不够，算一下还要等多久，预约之后桶里的令牌数是负数
+		if tb.rate <= 0 {
+			return &Reservation{}
+		}
+		wait = time.Duration(-tokens / tb.rate * float64(time.Second))
+	}
+	if wait > maxWait {
+		return &Reservation{}
+	}
+
+	tb.tokens = tokens
+	r := &Reservation{ok: true, tb: tb, tokens: n, timeToAct: now.Add(wait)}
+	tb.lastEvent = r.timeToAct
+	return r
+}
+
+// advance 补上从上次到现在放入的令牌，调用时需要持有 tb.mu
+func (tb *TokenBucket) advance(now time.Time) {
+	if elapsed := now.Sub(tb.last); elapsed > 0 {
+		tb.tokens = min(tb.tokens+elapsed.Seconds()*tb.rate, float64(tb.burst))
+		tb.last = now
+	}
+}
+
+// Reservation 是 ReserveN 预约的令牌
+type Reservation struct {
+	ok        bool
+	tb        *TokenBucket
+	tokens    int
+	timeToAct time.Time // 令牌可用的时间
+}
+
+// OK 是否预约成功
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay 还要等多久令牌才可用，预约失败时返回一个很大的值
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	return max(r.timeToAct.Sub(r.tb.clock.Now()), 0)
+}
+
+// Cancel 取消预约，令牌还没开始使用时还给令牌桶
+// 和 golang.org/x/time/rate 一样，之后的预约已经排在这些令牌后面了，只还它们没有用到的部分，
+// 否则之后的预约和新的预约会在同一时间拿到令牌，超过桶的容量
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+
+	tb := r.tb
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	now := tb.clock.Now()
+	if !now.Before(r.timeToAct) {
+		return // 令牌已经可用了，当作已经用掉
+	}
+	r.ok = false
+
+	restore := float64(r.tokens) - tb.lastEvent.Sub(r.timeToAct).Seconds()*tb.rate
+	if restore <= 0 {
+		return
+	}
+	tb.advance(now)
+	tb.tokens = min(tb.tokens+restore, float64(tb.burst))
+	if r.timeToAct.Equal(tb.lastEvent) {
+		// 取消的是最后一个预约，最后的时间退回到它之前
+		prev := r.timeToAct.Add(-time.Duration(float64(r.tokens) / tb.rate * float64(time.Second)))
+		if !prev.Before(now) {
+			tb.lastEvent = prev
+		}
+	}
+}
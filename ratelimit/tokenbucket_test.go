@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+
+func TestTokenBucketAllow(t *testing.T) {
+	c := clock.NewFake(epoch)
+	tb := NewTokenBucket(10, 5, WithClock(c))
+
+	// 初始时桶是满的，允许 burst 个突发请求
+	assert.True(t, tb.AllowN(5))
+	assert.False(t, tb.Allow())
+
+	// 100ms 放入一个令牌
+	c.Advance(100 * time.Millisecond)
+	assert.True(t, tb.Allow())
+	assert.False(t, tb.Allow())
+
+	// 桶最多装 burst 个令牌
+	c.Advance(time.Hour)
+	assert.True(t, tb.AllowN(5))
+	assert.False(t, tb.Allow())
+
+	// 超过容量永远拿不到
+	c.Advance(time.Hour)
+	assert.False(t, tb.AllowN(6))
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	c := clock.NewFake(epoch)
+	tb := NewTokenBucket(10, 1, WithClock(c))
+
+	r1 := tb.Reserve()
+	assert.True(t, r1.OK())
+	assert.Equal(t, time.Duration(0), r1.Delay())
+
+	// 令牌用完了，预约的令牌要等 100ms、200ms
+	r2 := tb.Reserve()
+	assert.Equal(t, 100*time.Millisecond, r2.Delay())
+	r3 := tb.Reserve()
+	assert.Equal(t, 200*time.Millisecond, r3.Delay())
+
+	// 取消后令牌还回去，后面的请求可以早点拿到
+	r3.Cancel()
+	c.Advance(200 * time.Millisecond)
+	assert.True(t, tb.Allow())
+
+	assert.False(t, tb.ReserveN(2).OK())
+}
+
+func TestTokenBucketCancelClaimed(t *testing.T) {
+	c := clock.NewFake(epoch)
+	tb := NewTokenBucket(1, 1, WithClock(c))
+	assert.True(t, tb.Allow())
+
+	a := tb.Reserve()
+	b := tb.Reserve()
+	assert.Equal(t, time.Second, a.Delay())
+	assert.Equal(t, 2*time.Second, b.Delay())
+
+	// b 已经排在 a 的令牌后面了，取消 a 不能把令牌还回去，否则 b 和 d 会同时拿到令牌
+	c.Advance(500 * time.Millisecond)
+	a.Cancel()
+	d := tb.Reserve()
+	assert.Equal(t, 1500*time.Millisecond, b.Delay())
+	assert.Equal(t, 2500*time.Millisecond, d.Delay())
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	c := clock.NewFake(epoch)
+	tb := NewTokenBucket(1, 1, WithClock(c))
+	assert.NoError(t, tb.Wait(context.Background()))
+
+	done := make(chan error)
+	go func() { done <- tb.Wait(context.Background()) }()
+	c.BlockUntil(1)
+	c.Advance(time.Second)
+	assert.NoError(t, <-done)
+
+	// 截止时间按注入的时钟计算，截止时间之前拿不到令牌，立即返回
+	c.Set(time.Now())
+	assert.True(t, tb.Allow())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tb.Wait(ctx), ErrLimited)
+
+	// 按注入的时钟截止时间已经过了，虽然按真实时间还有一分钟
+	c.Set(time.Now().Add(time.Hour))
+	assert.True(t, tb.Allow())
+	ctx3, cancel3 := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel3()
+	assert.ErrorIs(t, tb.Wait(ctx3), ErrLimited)
+
+	// 等待时被取消，预约的令牌还回去
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	go func() { done <- tb.Wait(ctx2) }()
+	c.BlockUntil(1)
+	cancel2()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	c.Advance(time.Second)
+	assert.True(t, tb.Allow())
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// FixedWindow 固定窗口计数
+// 时间按 window 切成一个个窗口，每个窗口最多放过 limit 个请求，进入新窗口时计数清零
+// 缺点是窗口边界附近：上个窗口的末尾和这个窗口的开头各放过 limit 个请求，短时间内就是两倍的 limit
+type FixedWindow struct {
+	clock  clock.Clock
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time // 当前窗口的开始时间
+	count int       // 当前窗口已经放过的请求数
+}
+
+// NewFixedWindow 创建一个固定窗口计数限流器，每个 window 最多放过 limit 个请求
+func NewFixedWindow(limit int, window time.Duration, opts ...Option) *FixedWindow {
+	o := newOptions(opts)
+	return &FixedWindow{
+		clock:  o.clock,
+		limit:  limit,
+		window: window,
+		start:  o.clock.Now(),
+	}
+}
+
+// Allow 等同于 AllowN(1)
+func (w *FixedWindow) Allow() bool {
+	return w.AllowN(1)
+}
+
+// AllowN 当前窗口能否再放过 n 个请求
+func (w *FixedWindow) AllowN(n int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		// 对齐到窗口的整数倍，进入新窗口
+		w.start = w.start.Add(elapsed - elapsed%w.window)
+		w.count = 0
+	}
+
+	if w.count+n > w.limit {
+		return false
+	}
+	w.count += n
+	return true
+}
+
+// SlidingWindow 滑动窗口计数
+// 只保存当前窗口和上一个窗口的计数，假设上一个窗口的请求是均匀分布的，
+// 用 上一个窗口的计数 * 上一个窗口还在滑动窗口内的比例 + 当前窗口的计数 估算最近 window 时间内的请求数
+// 比记录每个请求时间的滑动日志省内存，又解决了固定窗口边界处两倍流量的问题
+type SlidingWindow struct {
+	clock  clock.Clock
+	limit  int
+	window time.Duration
+
+	mu    sync.Mutex
+	start time.Time // 当前窗口的开始时间
+	prev  int       // 上一个窗口的请求数
+	count int       // 当前窗口的请求数
+}
+
+// NewSlidingWindow 创建一个滑动窗口计数限流器，任意 window 时间内最多放过约 limit 个请求
+func NewSlidingWindow(limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	o := newOptions(opts)
+	return &SlidingWindow{
+		clock:  o.clock,
+		limit:  limit,
+		window: window,
+		start:  o.clock.Now(),
+	}
+}
+
+// Allow 等同于 AllowN(1)
+func (w *SlidingWindow) Allow() bool {
+	return w.AllowN(1)
+}
+
+// AllowN 最近 window 时间内能否再放过 n 个请求
+func (w *SlidingWindow) AllowN(n int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	if elapsed := now.Sub(w.start); elapsed >= w.window {
+		if elapsed >= 2*w.window {
+			w.prev = 0 // 中间隔了一整个窗口没有请求
+		} else {
+			w.prev = w.count
+		}
+		w.start = w.start.Add(elapsed - elapsed%w.window)
+		w.count = 0
+	}
+
+	// 上一个窗口还有多少比例落在滑动窗口内
+	weight := 1 - float64(now.Sub(w.start))/float64(w.window)
+	estimated := float64(w.prev)*weight + float64(w.count)
+	if estimated+float64(n) > float64(w.limit) {
+		return false
+	}
+	w.count += n
+	return true
+}
@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixedWindow(t *testing.T) {
+	c := clock.NewFake(epoch)
+	w := NewFixedWindow(3, time.Second, WithClock(c))
+
+	assert.True(t, w.AllowN(2))
+	assert.True(t, w.Allow())
+	assert.False(t, w.Allow())
+
+	// 窗口边界：上个窗口末尾和这个窗口开头各放过 limit 个请求
+	c.Advance(time.Second)
+	assert.True(t, w.AllowN(3))
+	assert.False(t, w.Allow())
+
+	// 窗口对齐到整数倍
+	c.Advance(2500 * time.Millisecond)
+	assert.True(t, w.AllowN(3))
+	c.Advance(500 * time.Millisecond)
+	assert.True(t, w.AllowN(3))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := clock.NewFake(epoch)
+	w := NewSlidingWindow(4, time.Second, WithClock(c))
+
+	assert.True(t, w.AllowN(4))
+	assert.False(t, w.Allow())
+
+	// 进入新窗口 250ms，上个窗口还有 75% 在滑动窗口内，估算 4*0.75 = 3
+	c.Advance(1250 * time.Millisecond)
+	assert.True(t, w.Allow())
+	assert.False(t, w.Allow())
+
+	// 再过 500ms，估算 4*0.25+1 = 2
+	c.Advance(500 * time.Millisecond)
+	assert.True(t, w.AllowN(2))
+	assert.False(t, w.Allow())
+
+	// 隔了一整个窗口没有请求，上个窗口的计数清零
+	c.Advance(2 * time.Second)
+	assert.True(t, w.AllowN(4))
+}
@@ -0,0 +1,255 @@
+package semaphore
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 自适应并发限制
+// 写死的最大并发数（比如 customSemaphoreDemo 里的 maxConcurrent）要么太小浪费下游的处理能力，要么太大把下游压垮。
+// AdaptiveLimiter 根据每个请求的延迟和失败情况，不断调整底层 Semaphore 的容量，
+// 思路来自 TCP 拥塞控制，参考 https://github.com/Netflix/concurrency-limits
+
+// Sample 是一次请求的采样
+type Sample struct {
+	RTT      time.Duration // 请求耗时
+	Inflight int           // 请求开始时正在处理的请求数（包括自己）
+	Dropped  bool          // 请求失败、超时或被下游拒绝
+}
+
+// Algorithm 根据采样计算新的并发上限
+// Update 只会被 AdaptiveLimiter 串行调用，实现不需要自己加锁
+type Algorithm interface {
+	Update(limit int, s Sample) int
+}
+
+// AdaptiveLimiter 自适应并发限制器
+type AdaptiveLimiter struct {
+	sem      *Semaphore
+	alg      Algorithm
+	minLimit int
+	maxLimit int
+
+	mu       sync.Mutex
+	limit    int
+	inflight int
+}
+
+// NewAdaptiveLimiter 创建一个自适应并发限制器，并发上限从 initial 开始，在 [minLimit, maxLimit] 之间调整
+func NewAdaptiveLimiter(alg Algorithm, initial, minLimit, maxLimit int) *AdaptiveLimiter {
+	minLimit = max(minLimit, 1)
+	maxLimit = max(maxLimit, minLimit)
+	initial = min(max(initial, minLimit), maxLimit)
+
+	return &AdaptiveLimiter{
+		sem:      NewSemaphore(initial),
+		alg:      alg,
+		minLimit: minLimit,
+		maxLimit: maxLimit,
+		limit:    initial,
+	}
+}
+
+// Acquire 获取一个令牌，会阻塞直到并发数低于当前上限或 ctx 被取消
+// 拿到令牌后必须调用 Token 的 Success、Drop、Ignore 之一来归还
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (*Token, error) {
+	if err := l.sem.AcquireContext(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.inflight++
+	inflight := l.inflight
+	l.mu.Unlock()
+
+	return &Token{limiter: l, start: time.Now(), inflight: inflight}, nil
+}
+
+// Limit 返回当前的并发上限
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Inflight 返回正在处理的请求数
+func (l *AdaptiveLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inflight
+}
+
+func (l *AdaptiveLimiter) release(s *Sample) {
+	l.mu.Lock()
+	l.inflight--
+	if s != nil {
+		limit := min(max(l.alg.Update(l.limit, *s), l.minLimit), l.maxLimit)
+		if limit != l.limit {
+			l.limit = limit
+			l.sem.SetCapacity(limit)
+		}
+	}
+	l.mu.Unlock()
+
+	l.sem.Release()
+}
+
+// Token 是从 AdaptiveLimiter 拿到的令牌，重复归还只有第一次有效
+type Token struct {
+	limiter  *AdaptiveLimiter
+	start    time.Time
+	inflight int
+	released atomic.Bool
+}
+
+// Success 请求成功，用这次的耗时调整并发上限
+func (t *Token) Success() {
+	t.finish(false, true)
+}
+
+// Drop 请求失败、超时或被下游拒绝，通常会降低并发上限
+func (t *Token) Drop() {
+	t.finish(true, true)
+}
+
+// Ignore 请求结果不能反映下游的负载（比如参数错误），只归还令牌不调整上限
+func (t *Token) Ignore() {
+	t.finish(false, false)
+}
+
+func (t *Token) finish(dropped, record bool) {
+	if !t.released.CompareAndSwap(false, true) {
+		return
+	}
+
+	var s *Sample
+	if record {
+		s = &Sample{RTT: time.Since(t.start), Inflight: t.inflight, Dropped: dropped}
+	}
+	t.limiter.release(s)
+}
+
+// AIMD 加性增、乘性减，只对失败做出反应
+// 成功时上限加 1，失败（或耗时超过 Timeout）时上限乘以 BackoffRatio
+type AIMD struct {
+	BackoffRatio float64       // 失败时的退避比例，默认 0.9
+	Timeout      time.Duration // 耗时超过 Timeout 也算失败，为 0 时不检查
+}
+
+// Update 实现 Algorithm
+func (a *AIMD) Update(limit int, s Sample) int {
+	if s.Dropped || (a.Timeout > 0 && s.RTT > a.Timeout) {
+		ratio := a.BackoffRatio
+		if ratio <= 0 || ratio >= 1 {
+			ratio = 0.9
+		}
+		return int(float64(limit) * ratio)
+	}
+
+	// 并发数远没有达到上限时，说明上限不是瓶颈，没必要再调大
+	if s.Inflight*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+// Vegas 参考 TCP Vegas，用最小耗时估算无排队时的耗时，再估算下游的排队长度
+// 排队少就调大上限，排队多就调小上限，能在下游被压垮之前就做出反应
+type Vegas struct {
+	rttNoLoad time.Duration // 观测到的最小耗时，近似无排队时的耗时
+}
+
+// Update 实现 Algorithm
+func (v *Vegas) Update(limit int, s Sample) int {
+	if s.RTT <= 0 {
+		return limit
+	}
+	if v.rttNoLoad == 0 || s.RTT < v.rttNoLoad {
+		v.rttNoLoad = s.RTT
+		return limit
+	}
+
+	// 增减的步长随上限的对数增长，上限越大调整越快
+	step := max(math.Log10(float64(limit)), 1)
+	if s.Dropped {
+		return int(float64(limit) - step)
+	}
+	if s.Inflight*2 < limit {
+		return limit
+	}
+
+	queue := float64(limit) * (1 - float64(v.rttNoLoad)/float64(s.RTT))
+	alpha, beta := 3*step, 6*step
+	switch {
+	case queue <= step:
+		return int(float64(limit) + beta)
+	case queue < alpha:
+		return int(float64(limit) + step)
+	case queue > beta:
+		return int(float64(limit) - step)
+	default:
+		return limit
+	}
+}
+
+// Gradient 比较短期耗时和长期平均耗时的比值（梯度）来调整上限
+// 梯度小于 1 说明开始排队了，按比例调小上限；否则在当前上限上加上允许的排队长度 sqrt(limit)
+type Gradient struct {
+	Tolerance  float64 // 允许短期耗时比长期平均耗时高多少倍才开始调小上限，默认 1.5
+	Smoothing  float64 // 平滑系数，取值 (0, 1]，越大调整越快，默认 0.2
+	LongWindow int     // 长期平均耗时的窗口（采样数），默认 600
+
+	longRTT  float64 // 长期平均耗时，指数移动平均
+	estimate float64 // 带小数的上限，平滑后每次的变化可能不到 1，取整会让上限卡住不动
+}
+
+// Update 实现 Algorithm
+func (g *Gradient) Update(limit int, s Sample) int {
+	tolerance, smoothing, window := g.Tolerance, g.Smoothing, g.LongWindow
+	if tolerance < 1 {
+		tolerance = 1.5
+	}
+	if smoothing <= 0 || smoothing > 1 {
+		smoothing = 0.2
+	}
+	if window <= 0 {
+		window = 600
+	}
+
+	short := float64(s.RTT)
+	if short <= 0 {
+		return limit
+	}
+	switch {
+	case g.longRTT == 0:
+		g.longRTT = short
+	case short > tolerance*g.longRTT:
+		// 已经在排队了，长期耗时跟得太快会把排队当成常态，上限就会一直涨，所以放慢 10 倍
+		g.longRTT += (short - g.longRTT) / float64(window*10)
+	default:
+		g.longRTT += (short - g.longRTT) / float64(window)
+	}
+	// 负载恢复后长期耗时比短期耗时大很多，直接调小，尽快回到稳定状态
+	if g.longRTT/short > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// 上限被外部截断过，以外部的为准
+	if math.Abs(g.estimate-float64(limit)) >= 1 {
+		g.estimate = float64(limit)
+	}
+	if !s.Dropped && s.Inflight*2 < limit {
+		return limit
+	}
+
+	gradient := 0.5
+	if !s.Dropped {
+		gradient = min(max(tolerance*g.longRTT/short, 0.5), 1)
+	}
+	next := g.estimate*gradient + math.Sqrt(g.estimate)
+	g.estimate = g.estimate*(1-smoothing) + next*smoothing
+	return int(g.estimate)
+}
@@ -0,0 +1,163 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// simulate 模拟一个下游，请求源源不断，并发数总是打满上限
+// 下游依次经历 capacities 中的处理能力，每个阶段 steps 次采样：
+// 并发数没有超过处理能力时耗时为 base，超过后排队，耗时按比例增加，耗时超过 timeout 算失败
+// 返回每个阶段最后 1/4 采样后上限的平均值
+func simulate(alg Algorithm, capacities []int, steps int) []float64 {
+	const (
+		base    = 10 * time.Millisecond
+		timeout = 5 * base
+	)
+
+	limit := 1
+	avgs := make([]float64, 0, len(capacities))
+	for _, capacity := range capacities {
+		sum, window := 0, steps/4
+		for i := range steps {
+			rtt := base
+			if limit > capacity {
+				rtt = base * time.Duration(limit) / time.Duration(capacity)
+			}
+			s := Sample{RTT: rtt, Inflight: limit, Dropped: rtt > timeout}
+			limit = min(max(alg.Update(limit, s), 1), 1000)
+			if i >= steps-window {
+				sum += limit
+			}
+		}
+		avgs = append(avgs, float64(sum)/float64(window))
+	}
+	return avgs
+}
+
+func TestAlgorithmConvergence(t *testing.T) {
+	tests := []struct {
+		name string
+		alg  Algorithm
+		// 稳定后的上限与下游处理能力的比值范围
+		low, high float64
+	}{
+		// AIMD 只对失败做出反应，会在超时的边缘来回震荡
+		{"AIMD", &AIMD{Timeout: 30 * time.Millisecond}, 1, 3},
+		{"Vegas", &Vegas{}, 0.75, 1.75},
+		// Gradient 允许耗时涨到 Tolerance 倍，长期耗时也会慢慢跟上来，稳定点比 Vegas 高
+		{"Gradient", &Gradient{}, 1, 4},
+	}
+
+	// 下游的处理能力先降后升
+	capacities := []int{20, 10, 40}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avgs := simulate(tt.alg, capacities, 2000)
+			for i, avg := range avgs {
+				ratio := avg / float64(capacities[i])
+				assert.GreaterOrEqual(t, ratio, tt.low, "capacity %d, limit %.1f", capacities[i], avg)
+				assert.LessOrEqual(t, ratio, tt.high, "capacity %d, limit %.1f", capacities[i], avg)
+			}
+		})
+	}
+}
+
+// TestAdaptiveLimiterSimulation 和 simulate 一样模拟一个处理能力先降后升的下游，
+// 不过请求通过 AdaptiveLimiter 的 Acquire 获取令牌，再按下游的结果调用 Success 或 Drop
+// 下游同时处理的请求超过处理能力时直接拒绝
+func TestAdaptiveLimiterSimulation(t *testing.T) {
+	const (
+		workers = 100
+		steps   = 1600
+	)
+
+	l := NewAdaptiveLimiter(&AIMD{}, 1, 1, 1000)
+	for _, capacity := range []int{20, 10, 40} {
+		var (
+			next, inflight atomic.Int64
+			sum, samples   atomic.Int64
+			wg             sync.WaitGroup
+		)
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := next.Add(1); i <= steps; i = next.Add(1) {
+					token, err := l.Acquire(context.Background())
+					if !assert.NoError(t, err) {
+						return
+					}
+					rejected := inflight.Add(1) > int64(capacity)
+					time.Sleep(time.Millisecond)
+					inflight.Add(-1)
+					if rejected {
+						token.Drop()
+					} else {
+						token.Success()
+					}
+					// 每个阶段最后 1/4 的请求记录上限
+					if i > steps*3/4 {
+						sum.Add(int64(l.Limit()))
+						samples.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 0, l.Inflight())
+		avg := float64(sum.Load()) / float64(samples.Load())
+		ratio := avg / float64(capacity)
+		assert.GreaterOrEqual(t, ratio, 0.5, "capacity %d, limit %.1f", capacity, avg)
+		assert.LessOrEqual(t, ratio, 2.0, "capacity %d, limit %.1f", capacity, avg)
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	a := &AIMD{BackoffRatio: 0.5}
+	assert.Equal(t, 11, a.Update(10, Sample{RTT: time.Millisecond, Inflight: 10}))
+	// 并发数远没有达到上限，不调大
+	assert.Equal(t, 10, a.Update(10, Sample{RTT: time.Millisecond, Inflight: 2}))
+	assert.Equal(t, 5, a.Update(10, Sample{RTT: time.Millisecond, Inflight: 10, Dropped: true}))
+}
+
+// fixedAlgorithm 总是返回同一个上限
+type fixedAlgorithm int
+
+func (f fixedAlgorithm) Update(int, Sample) int { return int(f) }
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := NewAdaptiveLimiter(fixedAlgorithm(3), 1, 1, 2)
+	assert.Equal(t, 1, l.Limit())
+
+	token, err := l.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, l.Inflight())
+
+	// 上限是 1，再请求只能等到超时
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 调整后的上限被截断到 maxLimit
+	token.Success()
+	token.Success() // 重复归还没有影响
+	assert.Equal(t, 2, l.Limit())
+	assert.Equal(t, 0, l.Inflight())
+
+	t1, err := l.Acquire(context.Background())
+	assert.NoError(t, err)
+	t2, err := l.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, l.Inflight())
+	t1.Ignore()
+	t2.Drop()
+	assert.Equal(t, 0, l.Inflight())
+}
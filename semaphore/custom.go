@@ -1,6 +1,8 @@
 package semaphore
 
 import (
+	"container/list"
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -12,9 +14,22 @@ import (
 
 // Semaphore 数据结构，
 // 书上的例子是实现了 Locker 接口，但是这仅在容量为 1时比较像，所以还是改成用信号量的常用方法
+// 最初用 buffered channel 实现，只能一次请求/释放一个令牌，也不支持取消。
+// 现在参照 golang.org/x/sync/semaphore 改为 Mutex + 等待队列实现，支持带权重的请求、ctx 取消，
+// 等待者按 FIFO 顺序被唤醒：队头的大请求没被满足之前，后面的小请求也不能插队，避免大请求饥饿
 type Semaphore struct {
-	// sync.Locker
-	ch chan struct{}
+	size      int64      // 令牌总数
+	cur       int64      // 已被持有的令牌数
+	mu        sync.Mutex // 保护上面的计数和等待队列
+	waiters   list.List  // 等待者队列，元素是 *waiter，请求数都不超过 size
+	oversized list.List  // 请求数超过 size 的等待者，容量调大后才会移到 waiters 队尾
+}
+
+// waiter 是等待队列中的一个等待者
+type waiter struct {
+	n     int64           // 请求的令牌数
+	ready chan<- struct{} // 请求被满足时关闭
+	elem  *list.Element   // 在 waiters 或 oversized 中的位置
 }
 
 // NewSemaphore 创建一个信号量
@@ -23,19 +38,119 @@ func NewSemaphore(capacity int) *Semaphore {
 		capacity = 1 // 容量为 1 ，就是互斥锁
 	}
 
-	return &Semaphore{
-		ch: make(chan struct{}, capacity),
-	}
+	return &Semaphore{size: int64(capacity)}
 }
 
 // Acquire 获取令牌
 func (s *Semaphore) Acquire() {
-	s.ch <- struct{}{}
+	_ = s.AcquireContext(context.Background(), 1)
 }
 
 // Release 释放令牌
 func (s *Semaphore) Release() {
-	<-s.ch
+	s.ReleaseN(1)
+}
+
+// AcquireContext 请求 n 个令牌，会阻塞直到有足够的令牌可用或 ctx 被取消
+// 成功返回 nil；失败返回 ctx.Err()，此时不会持有任何令牌
+// 如果 n 大于信号量当前的容量，在容量被调大之前不会被满足，但也不会堵住其他等待者
+func (s *Semaphore) AcquireContext(ctx context.Context, n int64) error {
+	done := ctx.Done()
+
+	s.mu.Lock()
+	select {
+	case <-done:
+		// ctx 已经被取消了，即使有令牌也不再获取
+		s.mu.Unlock()
+		return ctx.Err()
+	default:
+	}
+	// 有足够的令牌，并且没有人排在前面，直接获取
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	w := &waiter{n: n, ready: ready}
+	if n > s.size {
+		// 不进等待队列，免得堵住后面的等待者
+		w.elem = s.oversized.PushBack(w)
+	} else {
+		w.elem = s.waiters.PushBack(w)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// 被取消的同时拿到了令牌，还回去，当作取消处理
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == w.elem
+			// 不在对应队列里时 Remove 什么也不做
+			s.waiters.Remove(w.elem)
+			s.oversized.Remove(w.elem)
+			// 自己是队头并且还有剩余令牌，后面的等待者可能可以被满足了
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+
+	case <-ready:
+		return nil
+	}
+}
+
+// TryAcquire 尝试请求 n 个令牌，不会阻塞，成功返回 true，失败返回 false
+// 失败时不会持有任何令牌
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	ok := s.size-s.cur >= n && s.waiters.Len() == 0
+	if ok {
+		s.cur += n
+	}
+	s.mu.Unlock()
+	return ok
+}
+
+// ReleaseN 释放 n 个令牌，释放的比持有的多会 panic
+func (s *Semaphore) ReleaseN(n int64) {
+	s.mu.Lock()
+	s.cur -= n
+	if s.cur < 0 {
+		s.mu.Unlock()
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+	s.mu.Unlock()
+}
+
+// notifyWaiters 按 FIFO 顺序唤醒能被满足的等待者，调用时需要持有 s.mu
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			break // 没有等待者了
+		}
+
+		w := next.Value.(*waiter)
+		if s.size-s.cur < w.n {
+			// 队头的令牌不够就停下来，即使后面有更小的请求可以被满足。
+			// 如果让小请求插队，大请求可能永远等不到足够的令牌
+			break
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
 }
 
 func customSemaphoreDemo() {
@@ -1,7 +1,142 @@
 package semaphore
 
-import "testing"
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func Test_customSemaphoreDemo(t *testing.T) {
 	customSemaphoreDemo()
 }
+
+func TestSemaphoreTryAcquire(t *testing.T) {
+	s := NewSemaphore(3)
+	assert.True(t, s.TryAcquire(2))
+	assert.False(t, s.TryAcquire(2))
+	assert.True(t, s.TryAcquire(1))
+	assert.False(t, s.TryAcquire(1))
+
+	s.ReleaseN(3)
+	assert.True(t, s.TryAcquire(3))
+}
+
+func TestSemaphoreAcquireContext(t *testing.T) {
+	s := NewSemaphore(2)
+	assert.NoError(t, s.AcquireContext(context.Background(), 2))
+
+	// 令牌不够，等到超时，超时后不持有任何令牌
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.AcquireContext(ctx, 1), context.DeadlineExceeded)
+
+	// 请求的令牌数超过容量，只能等到 ctx 被取消
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	assert.ErrorIs(t, s.AcquireContext(ctx2, 3), context.DeadlineExceeded)
+
+	s.ReleaseN(2)
+	assert.True(t, s.TryAcquire(2))
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := NewSemaphore(3)
+	s.Acquire()
+
+	// 先排队的大请求拿不到令牌时，后来的小请求也不能插队
+	big := make(chan struct{})
+	go func() {
+		_ = s.AcquireContext(context.Background(), 3)
+		close(big)
+	}()
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+	assert.False(t, s.TryAcquire(1))
+
+	small := make(chan struct{})
+	go func() {
+		_ = s.AcquireContext(context.Background(), 1)
+		close(small)
+	}()
+
+	s.Release()
+	<-big
+	select {
+	case <-small:
+		t.Fatal("small request should wait for the big one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.ReleaseN(3)
+	<-small
+	s.Release()
+}
+
+func TestSemaphoreCancelWakesNext(t *testing.T) {
+	s := NewSemaphore(2)
+	s.Acquire()
+
+	// 队头的大请求被取消后，排在后面的小请求应该被唤醒
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.AcquireContext(ctx, 2) }()
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+
+	small := make(chan struct{})
+	go func() {
+		_ = s.AcquireContext(context.Background(), 1)
+		close(small)
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+	<-small
+}
+
+func TestSemaphoreConcurrent(t *testing.T) {
+	const limit = 4
+	s := NewSemaphore(limit)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := int64(i%2 + 1)
+			_ = s.AcquireContext(context.Background(), n)
+			mu.Lock()
+			running += int(n)
+			peak = max(peak, running)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running -= int(n)
+			mu.Unlock()
+			s.ReleaseN(n)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, peak, limit)
+}
+
+func TestSemaphoreOverRelease(t *testing.T) {
+	s := NewSemaphore(1)
+	assert.Panics(t, func() { s.Release() })
+}
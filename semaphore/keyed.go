@@ -0,0 +1,141 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedSemaphore 按 key（租户、下游主机等）分别限制并发，每个 key 一个 Semaphore
+// key 第一次被请求时才创建信号量，没有持有者也没有等待者时立即回收，key 再多也不会一直占着内存
+// 还可以设置所有 key 加起来的并发上限，先拿 key 的令牌再拿全局的令牌，
+// 避免在 key 上排队的请求占着全局令牌，饿死其他 key
+type KeyedSemaphore[K comparable] struct {
+	mu         sync.Mutex
+	sems       map[K]*keyedEntry
+	capacity   int        // key 的默认容量
+	capacities map[K]int  // 单独设置了容量的 key，key 被回收后仍然保留
+	global     *Semaphore // 所有 key 共享的上限，为 nil 时不限制
+}
+
+type keyedEntry struct {
+	sem  *Semaphore
+	refs int64 // 持有和正在请求的令牌数，为 0 时回收
+}
+
+// NewKeyedSemaphore 创建一个按 key 限制并发的信号量，每个 key 默认的容量是 capacity
+// globalLimit 是所有 key 加起来的并发上限，小于等于 0 时不限制
+func NewKeyedSemaphore[K comparable](capacity, globalLimit int) *KeyedSemaphore[K] {
+	ks := &KeyedSemaphore[K]{
+		sems:       make(map[K]*keyedEntry),
+		capacity:   max(capacity, 1),
+		capacities: make(map[K]int),
+	}
+	if globalLimit > 0 {
+		ks.global = NewSemaphore(globalLimit)
+	}
+	return ks
+}
+
+// SetKeyCapacity 单独设置某个 key 的容量，key 正在使用时立即生效
+// capacity 为 0 时不再发放 key 的令牌，为负数时 panic，和 Semaphore.SetCapacity 一样
+func (ks *KeyedSemaphore[K]) SetKeyCapacity(key K, capacity int) {
+	if capacity < 0 {
+		panic("semaphore: negative capacity")
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.capacities[key] = capacity
+	if e, ok := ks.sems[key]; ok {
+		e.sem.SetCapacity(capacity)
+	}
+}
+
+// AcquireContext 请求 key 的 n 个令牌，会阻塞直到 key 和全局都有足够的令牌或 ctx 被取消
+// 失败时返回 ctx.Err()，不会持有任何令牌
+func (ks *KeyedSemaphore[K]) AcquireContext(ctx context.Context, key K, n int64) error {
+	e := ks.ref(key, n)
+	if err := e.sem.AcquireContext(ctx, n); err != nil {
+		ks.unref(key, e, n)
+		return err
+	}
+
+	if ks.global != nil {
+		if err := ks.global.AcquireContext(ctx, n); err != nil {
+			e.sem.ReleaseN(n)
+			ks.unref(key, e, n)
+			return err
+		}
+	}
+	return nil
+}
+
+// TryAcquire 尝试请求 key 的 n 个令牌，不会阻塞，成功返回 true
+func (ks *KeyedSemaphore[K]) TryAcquire(key K, n int64) bool {
+	e := ks.ref(key, n)
+	if !e.sem.TryAcquire(n) {
+		ks.unref(key, e, n)
+		return false
+	}
+
+	if ks.global != nil && !ks.global.TryAcquire(n) {
+		e.sem.ReleaseN(n)
+		ks.unref(key, e, n)
+		return false
+	}
+	return true
+}
+
+// Release 释放 key 的 n 个令牌，释放没有持有的 key 或者释放的比持有的多会 panic
+func (ks *KeyedSemaphore[K]) Release(key K, n int64) {
+	ks.mu.Lock()
+	e, ok := ks.sems[key]
+	ks.mu.Unlock()
+	if !ok {
+		panic("semaphore: release of unknown key")
+	}
+
+	e.sem.ReleaseN(n)
+	if ks.global != nil {
+		ks.global.ReleaseN(n)
+	}
+	ks.unref(key, e, n)
+}
+
+// Len 返回正在使用的 key 的数量
+func (ks *KeyedSemaphore[K]) Len() int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	return len(ks.sems)
+}
+
+// ref 取出 key 对应的信号量，没有就创建一个，并记录要请求的令牌数
+func (ks *KeyedSemaphore[K]) ref(key K, n int64) *keyedEntry {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	e, ok := ks.sems[key]
+	if !ok {
+		capacity, ok := ks.capacities[key]
+		if !ok {
+			capacity = ks.capacity
+		}
+		e = &keyedEntry{sem: NewSemaphore(1)}
+		e.sem.SetCapacity(capacity) // NewSemaphore 会把 0 改成 1，单独设置的容量可能是 0
+		ks.sems[key] = e
+	}
+	e.refs += n
+	return e
+}
+
+// unref 减去令牌数，没有持有者也没有等待者时回收 key
+func (ks *KeyedSemaphore[K]) unref(key K, e *keyedEntry, n int64) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	e.refs -= n
+	if e.refs <= 0 && ks.sems[key] == e {
+		delete(ks.sems, key)
+	}
+}
@@ -0,0 +1,114 @@
+package semaphore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedSemaphore(t *testing.T) {
+	ks := NewKeyedSemaphore[string](2, 0)
+
+	// 每个 key 单独计算
+	assert.True(t, ks.TryAcquire("a", 2))
+	assert.False(t, ks.TryAcquire("a", 1))
+	assert.True(t, ks.TryAcquire("b", 2))
+	assert.Equal(t, 2, ks.Len())
+
+	// 释放完就回收
+	ks.Release("a", 2)
+	ks.Release("b", 1)
+	assert.Equal(t, 1, ks.Len())
+	ks.Release("b", 1)
+	assert.Equal(t, 0, ks.Len())
+
+	assert.Panics(t, func() { ks.Release("c", 1) })
+}
+
+func TestKeyedSemaphoreKeyCapacity(t *testing.T) {
+	ks := NewKeyedSemaphore[string](1, 0)
+	ks.SetKeyCapacity("vip", 3)
+
+	assert.True(t, ks.TryAcquire("vip", 3))
+	assert.False(t, ks.TryAcquire("normal", 2))
+
+	// 正在使用的 key 修改容量立即生效
+	ks.SetKeyCapacity("vip", 4)
+	assert.True(t, ks.TryAcquire("vip", 1))
+	ks.Release("vip", 4)
+
+	// 单独设置的容量在 key 被回收后仍然有效
+	assert.Equal(t, 0, ks.Len())
+	assert.True(t, ks.TryAcquire("vip", 4))
+	ks.Release("vip", 4)
+
+	// 负数的容量立即 panic，不会保存下来，之后使用 key 也不受影响
+	assert.Panics(t, func() { ks.SetKeyCapacity("vip", -1) })
+	assert.Panics(t, func() { ks.SetKeyCapacity("new", -1) })
+	assert.True(t, ks.TryAcquire("vip", 4))
+	assert.True(t, ks.TryAcquire("new", 1))
+	ks.Release("vip", 4)
+	ks.Release("new", 1)
+}
+
+func TestKeyedSemaphoreGlobalLimit(t *testing.T) {
+	ks := NewKeyedSemaphore[int](2, 3)
+	assert.True(t, ks.TryAcquire(1, 2))
+	assert.True(t, ks.TryAcquire(2, 1))
+
+	// key 2 还有令牌，但是全局的令牌用完了
+	assert.False(t, ks.TryAcquire(2, 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ks.AcquireContext(ctx, 3, 1), context.DeadlineExceeded)
+	assert.Equal(t, 2, ks.Len()) // 失败的请求不会留下 key
+
+	done := make(chan struct{})
+	go func() {
+		_ = ks.AcquireContext(context.Background(), 3, 1)
+		close(done)
+	}()
+	ks.Release(1, 1)
+	<-done
+}
+
+func TestKeyedSemaphoreConcurrent(t *testing.T) {
+	const perKey = 2
+	ks := NewKeyedSemaphore[int](perKey, 5)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running = make(map[int]int)
+		total   int
+	)
+	for i := range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := i % 4
+			assert.NoError(t, ks.AcquireContext(context.Background(), key, 1))
+
+			mu.Lock()
+			running[key]++
+			total++
+			assert.LessOrEqual(t, running[key], perKey)
+			assert.LessOrEqual(t, total, 5)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running[key]--
+			total--
+			mu.Unlock()
+			ks.Release(key, 1)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 0, ks.Len())
+}
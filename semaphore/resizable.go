@@ -0,0 +1,67 @@
+package semaphore
+
+// 运行时修改信号量的容量，常用于根据下游的负载情况做准入控制
+// 调大容量：立即按 FIFO 顺序唤醒能被满足的等待者
+// 调小容量：已经发出去的令牌不会被收回，持有者释放到新容量以下之前，新的请求都要等待
+
+// SetCapacity 修改信号量的容量，n 为 0 时不再发放任何令牌，n 为负数会 panic
+func (s *Semaphore) SetCapacity(n int) {
+	if n < 0 {
+		panic("semaphore: negative capacity")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size = int64(n)
+
+	// 容量调小后，请求数超过容量的等待者移到 oversized，免得堵住队列
+	for e := s.waiters.Front(); e != nil; {
+		next := e.Next()
+		if w := e.Value.(*waiter); w.n > s.size {
+			s.waiters.Remove(e)
+			w.elem = s.oversized.PushBack(w)
+		}
+		e = next
+	}
+
+	// 容量调大后，能被满足的等待者排到队尾
+	for e := s.oversized.Front(); e != nil; {
+		next := e.Next()
+		if w := e.Value.(*waiter); w.n <= s.size {
+			s.oversized.Remove(e)
+			w.elem = s.waiters.PushBack(w)
+		}
+		e = next
+	}
+
+	s.notifyWaiters()
+}
+
+// Capacity 返回信号量当前的容量
+func (s *Semaphore) Capacity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(s.size)
+}
+
+// Current 返回已被持有的令牌数，容量调小后可能比容量大
+func (s *Semaphore) Current() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(s.cur)
+}
+
+// Available 返回还能发放的令牌数
+func (s *Semaphore) Available() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int(max(s.size-s.cur, 0))
+}
+
+// Waiting 返回正在等待的请求数
+func (s *Semaphore) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len() + s.oversized.Len()
+}
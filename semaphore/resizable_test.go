@@ -0,0 +1,83 @@
+package semaphore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphoreGrow(t *testing.T) {
+	s := NewSemaphore(1)
+	s.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = s.AcquireContext(context.Background(), 2)
+		close(acquired)
+	}()
+	assert.Eventually(t, func() bool { return s.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	// 容量调大后，原本超过容量的请求可以被满足了
+	s.SetCapacity(3)
+	<-acquired
+	assert.Equal(t, 3, s.Capacity())
+	assert.Equal(t, 3, s.Current())
+	assert.Equal(t, 0, s.Available())
+	assert.Equal(t, 0, s.Waiting())
+}
+
+func TestSemaphoreShrink(t *testing.T) {
+	s := NewSemaphore(4)
+	assert.True(t, s.TryAcquire(4))
+
+	// 调小容量不会收回已发放的令牌
+	s.SetCapacity(2)
+	assert.Equal(t, 4, s.Current())
+	assert.Equal(t, 0, s.Available())
+
+	acquired := make(chan struct{})
+	go func() {
+		s.Acquire()
+		close(acquired)
+	}()
+	assert.Eventually(t, func() bool { return s.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	// 持有者释放到新容量以下之前，新的请求都要等待
+	s.ReleaseN(2)
+	select {
+	case <-acquired:
+		t.Fatal("acquired before holders drained below capacity")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Release()
+	<-acquired
+	assert.Equal(t, 2, s.Current())
+}
+
+func TestSemaphoreShrinkMovesOversized(t *testing.T) {
+	s := NewSemaphore(4)
+	s.Acquire()
+
+	big := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { big <- s.AcquireContext(ctx, 4) }()
+	assert.Eventually(t, func() bool { return s.Waiting() == 1 }, time.Second, time.Millisecond)
+
+	// 容量调小后，大请求不能再堵住后面的小请求
+	s.SetCapacity(2)
+	assert.True(t, s.TryAcquire(1))
+
+	cancel()
+	assert.ErrorIs(t, <-big, context.Canceled)
+	assert.Equal(t, 0, s.Waiting())
+}
+
+func TestSemaphoreZeroCapacity(t *testing.T) {
+	s := NewSemaphore(1)
+	s.SetCapacity(0)
+	assert.False(t, s.TryAcquire(1))
+	assert.Panics(t, func() { s.SetCapacity(-1) })
+}
@@ -0,0 +1,266 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"concurrence/internal/panics"
+)
+
+// DataLoader 把一小段时间内零散的 Load(key) 攒成一批，只调用一次批量查询，用来解决 N+1 查询问题
+// 比如渲染 100 条评论时逐条查询作者，变成一次 SELECT ... WHERE id IN (...)
+// 思路来自 https://github.com/graphql/dataloader：
+//   - 第一个 Load 开始计时，等待 wait 时间或者攒够 maxBatch 个 key 就发出批量查询
+//   - 同一个 key 只查一次，正在查询的 key 再次 Load 时等待同一个结果，和 singleflight.Group 一样
+//   - 查询结果缓存在 DataLoader 里，所以 DataLoader 应该按请求创建，请求结束就丢掉，不会读到其他请求的旧数据
+//   - 一批的调用者都放弃等待后，取消这一批查询的 ctx，和 Group 一样
+
+// ErrNotFound 批量查询的结果里没有这个 key
+var ErrNotFound = errors.New("singleflight: key not found in batch result")
+
+// BatchFunc 批量查询函数，返回的 map 里没有的 key 得到 ErrNotFound，返回 error 时这一批的 key 都得到这个 error
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// LoaderOption DataLoader 的选项
+type LoaderOption func(*loaderOptions)
+
+type loaderOptions struct {
+	wait     time.Duration
+	maxBatch int
+}
+
+// WithBatchWait 第一个 Load 之后最多等待 d 时间就发出批量查询，默认 2ms
+func WithBatchWait(d time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		o.wait = d
+	}
+}
+
+// WithMaxBatch 一批最多 n 个 key，攒够了立即发出批量查询，默认 100
+func WithMaxBatch(n int) LoaderOption {
+	return func(o *loaderOptions) {
+		o.maxBatch = n
+	}
+}
+
+// DataLoader 批量加载器
+type DataLoader[K comparable, V any] struct {
+	batchFn BatchFunc[K, V]
+	loaderOptions
+
+	mu    sync.Mutex
+	cache map[K]*loadResult[K, V] // 查询过和正在查询的 key
+	batch *loadBatch[K, V]        // 正在攒的一批，没有时为 nil
+}
+
+// loadResult 是一个 key 的查询结果
+type loadResult[K comparable, V any] struct {
+	done  chan struct{} // 查询结束时关闭
+	val   V
+	err   error
+	batch *loadBatch[K, V] // 所在的一批，查询结束后为 nil
+}
+
+// loadBatch 是一批查询
+type loadBatch[K comparable, V any] struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	keys    []K
+	results []*loadResult[K, V]
+	timer   *time.Timer
+	waiters int // 还在等待这一批结果的调用者数量
+}
+
+// NewDataLoader 创建一个批量加载器
+func NewDataLoader[K comparable, V any](batchFn BatchFunc[K, V], opts ...LoaderOption) *DataLoader[K, V] {
+	l := &DataLoader[K, V]{
+		batchFn:       batchFn,
+		loaderOptions: loaderOptions{wait: 2 * time.Millisecond, maxBatch: 100},
+		cache:         make(map[K]*loadResult[K, V]),
+	}
+	for _, opt := range opts {
+		opt(&l.loaderOptions)
+	}
+	l.maxBatch = max(l.maxBatch, 1)
+	return l
+}
+
+// Load 加载 key 对应的值，会等待这一批的批量查询结束
+// 批量查询的 ctx 保留了这一批第一个调用者 ctx 中的值，这一批的调用者都放弃等待后才被取消
+func (l *DataLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return l.wait(ctx, l.load(ctx, key))
+}
+
+// LoadMany 加载多个 key，这些 key 会进入同一批（超过 maxBatch 时分成多批）
+// 返回的值和 keys 一一对应，失败的 key 对应零值，error 是所有失败合并后的错误
+func (l *DataLoader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	results := make([]*loadResult[K, V], len(keys))
+	for i, key := range keys {
+		results[i] = l.load(ctx, key)
+	}
+
+	vals := make([]V, len(keys))
+	var errs []error
+	for i, r := range results {
+		v, err := l.wait(ctx, r)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		vals[i] = v
+	}
+	return vals, errors.Join(errs...)
+}
+
+// Prime 直接把值放进缓存，key 已经在缓存中时什么也不做
+func (l *DataLoader[K, V]) Prime(key K, v V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	r := &loadResult[K, V]{done: make(chan struct{}), val: v}
+	close(r.done)
+	l.cache[key] = r
+}
+
+// Clear 从缓存中删除 key，下次 Load 会重新查询
+func (l *DataLoader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	delete(l.cache, key)
+	l.mu.Unlock()
+}
+
+// load 把 key 加入正在攒的一批，key 已经查询过或正在查询时直接返回那个结果
+func (l *DataLoader[K, V]) load(ctx context.Context, key K) *loadResult[K, V] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r, ok := l.cache[key]; ok {
+		if r.batch != nil {
+			r.batch.waiters++
+		}
+		return r
+	}
+
+	b := l.batch
+	if b == nil {
+		b = &loadBatch[K, V]{}
+		b.ctx, b.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		b.timer = time.AfterFunc(l.loaderOptions.wait, func() { l.dispatch(b) })
+		l.batch = b
+	}
+	r := &loadResult[K, V]{done: make(chan struct{}), batch: b}
+	l.cache[key] = r
+	b.waiters++
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+
+	// 攒够了，不用等定时器
+	if len(b.keys) >= l.maxBatch {
+		b.timer.Stop()
+		l.batch = nil
+		go l.run(b)
+	}
+	return r
+}
+
+// dispatch 定时器到期，发出批量查询
+func (l *DataLoader[K, V]) dispatch(b *loadBatch[K, V]) {
+	l.mu.Lock()
+	if l.batch != b {
+		// 已经因为攒够了被发出去了
+		l.mu.Unlock()
+		return
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	l.run(b)
+}
+
+// run 执行批量查询，把结果分发给每个 key
+func (l *DataLoader[K, V]) run(b *loadBatch[K, V]) {
+	defer b.cancel()
+
+	var vals map[K]V
+	err := b.ctx.Err()
+	if err == nil {
+		// 发出之前调用者都已经放弃了就不用查询
+		vals, err = l.callBatchFn(b)
+	}
+
+	l.mu.Lock()
+	for i, key := range b.keys {
+		r := b.results[i]
+		r.batch = nil
+		switch v, ok := vals[key]; {
+		case err != nil:
+			r.err = err
+		case ok:
+			r.val = v
+		default:
+			r.err = fmt.Errorf("%w: %v", ErrNotFound, key)
+		}
+		// 失败的结果不缓存，下次 Load 重新查询
+		if r.err != nil && l.cache[key] == r {
+			delete(l.cache, key)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, r := range b.results {
+		close(r.done)
+	}
+}
+
+// callBatchFn 调用批量查询函数，panic 转成 error，免得等待的调用者永远等下去
+func (l *DataLoader[K, V]) callBatchFn(b *loadBatch[K, V]) (vals map[K]V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panics.New(r)
+		}
+	}()
+	return l.batchFn(b.ctx, b.keys)
+}
+
+// wait 等待 r 的结果，ctx 被取消时放弃等待，最后一个放弃的调用者取消这一批查询
+func (l *DataLoader[K, V]) wait(ctx context.Context, r *loadResult[K, V]) (V, error) {
+	select {
+	case <-r.done:
+		return r.val, r.err
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if b := r.batch; b != nil {
+		b.waiters--
+		if b.waiters == 0 {
+			l.abandon(b)
+		}
+	}
+	l.mu.Unlock()
+
+	var zero V
+	return zero, ctx.Err()
+}
+
+// abandon 没有人等待这一批的结果了，取消查询，并让之后的 Load 重新查询，调用时需要持有 l.mu
+func (l *DataLoader[K, V]) abandon(b *loadBatch[K, V]) {
+	b.cancel()
+	for i, key := range b.keys {
+		if l.cache[key] == b.results[i] {
+			delete(l.cache, key)
+		}
+	}
+	if l.batch == b {
+		// 还没有发出，之后的 Load 放到新的一批里，这一批直接结束
+		l.batch = nil
+		b.timer.Stop()
+		go l.run(b)
+	}
+}
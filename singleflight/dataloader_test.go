@@ -0,0 +1,192 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// userLoader 模拟按 id 批量查询用户名，记录每一批查询的 key
+type userLoader struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (u *userLoader) load(ctx context.Context, ids []int) (map[int]string, error) {
+	u.mu.Lock()
+	u.batches = append(u.batches, ids)
+	u.mu.Unlock()
+
+	users := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if id < 0 {
+			continue // 不存在的用户
+		}
+		users[id] = fmt.Sprintf("user_%d", id)
+	}
+	return users, nil
+}
+
+func (u *userLoader) batchCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return len(u.batches)
+}
+
+func TestDataLoaderBatch(t *testing.T) {
+	u := &userLoader{}
+	l := NewDataLoader(u.load, WithBatchWait(20*time.Millisecond))
+
+	// 并发的 Load 攒成一批，重复的 key 只查一次
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := i % 10
+			name, err := l.Load(context.Background(), id)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("user_%d", id), name)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, u.batchCount())
+	assert.Len(t, u.batches[0], 10)
+
+	// 查询过的 key 直接用缓存
+	name, err := l.Load(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "user_3", name)
+	assert.Equal(t, 1, u.batchCount())
+
+	// Clear 之后重新查询
+	l.Clear(3)
+	_, _ = l.Load(context.Background(), 3)
+	assert.Equal(t, 2, u.batchCount())
+}
+
+func TestDataLoaderMaxBatch(t *testing.T) {
+	u := &userLoader{}
+	l := NewDataLoader(u.load, WithBatchWait(time.Hour), WithMaxBatch(5))
+
+	// 攒够了立即查询，不用等定时器
+	names, err := l.LoadMany(context.Background(), []int{1, 2, 3, 4, 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user_1", "user_2", "user_3", "user_4", "user_5"}, names)
+	assert.Equal(t, 1, u.batchCount())
+}
+
+func TestDataLoaderErrors(t *testing.T) {
+	u := &userLoader{}
+	l := NewDataLoader(u.load)
+
+	names, err := l.LoadMany(context.Background(), []int{1, -1})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, []string{"user_1", ""}, names)
+
+	// Prime 的值直接返回
+	l.Prime(-1, "ghost")
+	name, err := l.Load(context.Background(), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, "ghost", name)
+
+	// 批量查询失败，这一批的 key 都失败，并且不缓存
+	errDB := errors.New("db down")
+	var fail = true
+	l2 := NewDataLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		if fail {
+			return nil, errDB
+		}
+		return map[int]int{1: 1}, nil
+	})
+	_, err = l2.Load(context.Background(), 1)
+	assert.ErrorIs(t, err, errDB)
+	fail = false
+	v, err := l2.Load(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// 批量查询 panic 转成 error
+	l3 := NewDataLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		panic("boom")
+	})
+	_, err = l3.Load(context.Background(), 1)
+	var pe *PanicError
+	assert.ErrorAs(t, err, &pe)
+}
+
+func TestDataLoaderCancel(t *testing.T) {
+	var calls atomic.Int32
+	cancelled := make(chan error, 1)
+	l := NewDataLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		if calls.Add(1) > 1 {
+			return map[int]int{1: 1}, nil
+		}
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	})
+
+	// 两个调用者在同一批里
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err := l.Load(ctx1, 1)
+		errs <- err
+	}()
+	go func() {
+		_, err := l.Load(ctx2, 2)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	// 还有调用者在等待，查询继续执行
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-cancelled:
+		t.Fatal("batch should not be cancelled while a caller is still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// 最后一个调用者放弃后，取消查询
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+	// 之后的 Load 重新查询
+	v, err := l.Load(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestDataLoaderCancelBeforeDispatch(t *testing.T) {
+	var calls atomic.Int32
+	l := NewDataLoader(func(ctx context.Context, keys []int) (map[int]int, error) {
+		calls.Add(1)
+		vals := make(map[int]int, len(keys))
+		for _, key := range keys {
+			vals[key] = key
+		}
+		return vals, nil
+	}, WithBatchWait(time.Hour), WithMaxBatch(10))
+
+	// 发出之前调用者都放弃了，不会查询，之后的 Load 放到新的一批里
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.Load(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	keys := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	vals, err := l.LoadMany(context.Background(), keys)
+	assert.NoError(t, err)
+	assert.Equal(t, keys, vals)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,238 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"concurrence/clock"
+	"concurrence/internal/panics"
+)
+
+// x/sync/singleflight 的两个问题：
+// 1. 参数和结果都是 interface{}，每次都要类型断言
+// 2. fn 拿不到 ctx，Demo3 里所有调用者都超时走掉了，下游函数还在傻傻地执行
+// Group 是泛型版本，fn 的 ctx 只有在所有等待的调用者都放弃之后才会被取消：
+// 只要还有一个调用者在等，fn 就继续执行，结果对还在等的调用者仍然有用
+//
+// Demo 里提到，第一个调用结束后才到的请求会重新调用 fn，间隔几毫秒的突发请求仍然会重复执行
+// 通过 WithResultTTL 可以把成功的结果多保留一段时间，这段时间内到达的请求直接共享这个结果
+
+// ErrGoexit fn 调用了 runtime.Goexit，等待的调用者得到这个错误
+var ErrGoexit = errors.New("singleflight: fn called runtime.Goexit")
+
+// PanicError 是 fn panic 时的值和 fn 所在 goroutine 的调用栈，会在每个等待的调用者中重新 panic
+type PanicError = panics.Error
+
+// Option Group 的选项
+type Option func(*options)
+
+type options struct {
+	resultTTL time.Duration
+	clock     clock.Clock
+}
+
+// WithResultTTL fn 成功返回后，结果保留 d 时间，这段时间内的调用直接返回这个结果，过期后自动删除
+func WithResultTTL(d time.Duration) Option {
+	return func(o *options) {
+		o.resultTTL = d
+	}
+}
+
+// WithClock 设置使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// Group 合并相同 key 的并发调用，零值可以直接使用
+type Group[K comparable, V any] struct {
+	options
+
+	mu sync.Mutex
+	m  map[K]*call[V]
+}
+
+// NewGroup 创建一个 Group，不需要选项时直接用零值就可以
+func NewGroup[K comparable, V any](opts ...Option) *Group[K, V] {
+	g := &Group[K, V]{}
+	for _, opt := range opts {
+		opt(&g.options)
+	}
+	return g
+}
+
+// call 是一次正在执行或者结果还在保留期内的调用
+type call[V any] struct {
+	done     chan struct{} // fn 执行结束时关闭
+	val      V
+	err      error
+	panicErr *PanicError // fn panic 时不为 nil
+
+	waiters  int                // 还在等待结果的调用者数量
+	dups     int                // 合并进来的调用者数量，大于 0 表示结果被共享了
+	cancel   context.CancelFunc // 取消 fn 的 ctx
+	finished bool               // fn 已经执行结束，结果在保留期内
+	expires  time.Time          // 结果保留到什么时候
+}
+
+// Do 执行 fn 并返回结果，同一个 key 同一时刻只有一个 fn 在执行，并发的调用者等待并共享它的结果
+// fn 在新的 goroutine 中执行，ctx 被取消时调用者立即返回 ctx.Err()；
+// 所有调用者都返回之后，fn 的 ctx 才会被取消，之后的调用会重新执行 fn
+// fn 的 ctx 保留了第一个调用者 ctx 中的值
+// shared 表示结果是否被多个调用者共享
+//
+// fn panic 时，每个等待的调用者都会以 *PanicError 重新 panic，其中带有 fn 的调用栈；
+// 如果这时已经没有调用者在等待，会在新的 goroutine 中 panic，免得 panic 被悄悄吞掉
+// fn 调用 runtime.Goexit 时，等待的调用者得到 ErrGoexit
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[K]*call[V])
+	}
+	if c, ok := g.m[key]; ok {
+		if !c.finished {
+			c.waiters++
+			c.dups++
+			g.mu.Unlock()
+			return g.wait(ctx, key, c)
+		}
+		if g.now().Before(c.expires) {
+			g.mu.Unlock()
+			return c.val, c.err, true
+		}
+		delete(g.m, key) // 过期了，重新执行
+	}
+
+	fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &call[V]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.run(fctx, key, c, fn)
+	return g.wait(ctx, key, c)
+}
+
+// Forget 忘记 key，之后的调用会重新执行 fn，正在等待的调用者仍然会得到这次调用的结果
+func (g *Group[K, V]) Forget(key K) {
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+}
+
+// ForgetUnshared 只有 key 的结果没有被其他调用者共享时才忘记 key
+// 返回 true 表示 key 已经被忘记或者本来就不存在
+func (g *Group[K, V]) ForgetUnshared(key K) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c, ok := g.m[key]
+	if !ok {
+		return true
+	}
+	if c.dups == 0 {
+		delete(g.m, key)
+		return true
+	}
+	return false
+}
+
+// wait 等待 fn 的结果，ctx 被取消时放弃等待，最后一个放弃的调用者取消 fn
+func (g *Group[K, V]) wait(ctx context.Context, key K, c *call[V]) (v V, err error, shared bool) {
+	select {
+	case <-c.done:
+		if c.panicErr != nil {
+			panic(c.panicErr)
+		}
+		return c.val, c.err, c.dups > 0
+
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// 没有人关心结果了，取消 fn，并让之后的调用重新执行
+			c.cancel()
+			if g.m[key] == c && !c.finished {
+				delete(g.m, key)
+			}
+		}
+		g.mu.Unlock()
+		return v, ctx.Err(), false
+	}
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, c *call[V], fn func(ctx context.Context) (V, error)) {
+	normalReturn := false
+	recovered := false
+
+	// 用两层 defer 区分 panic 和 runtime.Goexit：
+	// Goexit 时内层的 recover 返回 nil，也走不到 recovered = true
+	defer func() {
+		if !normalReturn && !recovered {
+			c.err = ErrGoexit
+		}
+
+		g.mu.Lock()
+		if g.m[key] == c {
+			if c.err == nil && c.panicErr == nil && g.resultTTL > 0 {
+				c.finished = true
+				c.expires = g.now().Add(g.resultTTL)
+				go g.expire(key, c)
+			} else {
+				delete(g.m, key)
+			}
+		}
+		orphan := c.panicErr != nil && c.waiters == 0
+		g.mu.Unlock()
+
+		c.cancel()
+		close(c.done)
+		if orphan {
+			go panic(c.panicErr)
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				if r := recover(); r != nil {
+					c.panicErr = panics.New(r)
+				}
+			}
+		}()
+
+		c.val, c.err = fn(ctx)
+		normalReturn = true
+	}()
+
+	if !normalReturn {
+		recovered = true
+	}
+}
+
+// expire 保留期过后从 g.m 中删除结果，不再被请求的 key 也不会一直占着内存
+func (g *Group[K, V]) expire(key K, c *call[V]) {
+	<-g.after(g.resultTTL)
+
+	g.mu.Lock()
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+	g.mu.Unlock()
+}
+
+func (g *Group[K, V]) after(d time.Duration) <-chan time.Time {
+	if g.clock == nil {
+		return time.After(d)
+	}
+	return g.clock.After(d)
+}
+
+func (g *Group[K, V]) now() time.Time {
+	if g.clock == nil {
+		return time.Now()
+	}
+	return g.clock.Now()
+}
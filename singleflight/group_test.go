@@ -0,0 +1,266 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"concurrence/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDo(t *testing.T) {
+	var (
+		g     Group[string, int]
+		calls atomic.Int32
+		wg    sync.WaitGroup
+	)
+	release := make(chan struct{})
+
+	var sharedCnt atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+				calls.Add(1)
+				<-release
+				return 42, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v) // 不需要类型断言
+			if shared {
+				sharedCnt.Add(1)
+			}
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.m["key"] != nil && g.m["key"].waiters == 10
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(10), sharedCnt.Load())
+
+	// 结束之后的调用重新执行
+	errFn := errors.New("fn failed")
+	_, err, shared := g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+		return 0, errFn
+	})
+	assert.ErrorIs(t, err, errFn)
+	assert.False(t, shared)
+}
+
+func TestGroupCancel(t *testing.T) {
+	var g Group[string, string]
+	fnCtx := make(chan context.Context, 1)
+	fn := func(ctx context.Context) (string, error) {
+		fnCtx <- ctx
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err, _ := g.Do(ctx1, "key", fn)
+		errs <- err
+	}()
+	ctx := <-fnCtx
+	go func() {
+		_, err, _ := g.Do(ctx2, "key", fn)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.m["key"].waiters == 2
+	}, time.Second, time.Millisecond)
+
+	// 第一个调用者走了，还有人在等，fn 继续执行
+	cancel1()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	select {
+	case <-ctx.Done():
+		t.Fatal("fn cancelled while a caller is still waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// 所有调用者都走了，fn 的 ctx 被取消
+	cancel2()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	<-ctx.Done()
+
+	// 之后的调用重新执行 fn
+	v, err, _ := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) {
+		return "fresh", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "fresh", v)
+}
+
+func TestGroupContextValue(t *testing.T) {
+	type ctxKey struct{}
+	var g Group[int, string]
+
+	// fn 的 ctx 保留第一个调用者 ctx 里的值
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace-1")
+	v, err, _ := g.Do(ctx, 1, func(ctx context.Context) (string, error) {
+		return ctx.Value(ctxKey{}).(string), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "trace-1", v)
+}
+
+func TestGroupResultTTL(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	g := NewGroup[string, int](WithResultTTL(100*time.Millisecond), WithClock(c))
+
+	var calls atomic.Int32
+	fn := func(ctx context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	}
+
+	v, _, shared := g.Do(context.Background(), "key", fn)
+	assert.Equal(t, 1, v)
+	assert.False(t, shared)
+
+	// 保留期内直接共享上次的结果
+	c.Advance(50 * time.Millisecond)
+	v, _, shared = g.Do(context.Background(), "key", fn)
+	assert.Equal(t, 1, v)
+	assert.True(t, shared)
+
+	// 过期后重新执行
+	c.Advance(50 * time.Millisecond)
+	v, _, _ = g.Do(context.Background(), "key", fn)
+	assert.Equal(t, 2, v)
+
+	// Forget 之后重新执行
+	g.Forget("key")
+	v, _, _ = g.Do(context.Background(), "key", fn)
+	assert.Equal(t, 3, v)
+
+	// 失败的结果不保留
+	errFn := errors.New("fn failed")
+	_, err, _ := g.Do(context.Background(), "err", func(ctx context.Context) (int, error) { return 0, errFn })
+	assert.ErrorIs(t, err, errFn)
+	v, err, _ = g.Do(context.Background(), "err", fn)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, v)
+}
+
+func TestGroupResultTTLEvict(t *testing.T) {
+	c := clock.NewFake(time.Now())
+	g := NewGroup[int, int](WithResultTTL(time.Minute), WithClock(c))
+	size := func() int {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.m)
+	}
+
+	for i := range 100 {
+		g.Do(context.Background(), i, func(ctx context.Context) (int, error) { return i, nil })
+	}
+	assert.Equal(t, 100, size())
+
+	// 过期的结果不需要再被请求也会被删除
+	c.BlockUntil(100)
+	c.Advance(time.Minute)
+	assert.Eventually(t, func() bool { return size() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestGroupForgetUnshared(t *testing.T) {
+	var g Group[string, int]
+	assert.True(t, g.ForgetUnshared("none"))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+	}()
+	<-started
+
+	// 只有一个调用者，可以忘记
+	assert.True(t, g.ForgetUnshared("key"))
+
+	done := make(chan struct{})
+	started2 := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _, _ = g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+			close(started2)
+			<-release
+			return 2, nil
+		})
+	}()
+	<-started2
+	go func() {
+		_, _, _ = g.Do(context.Background(), "key", func(ctx context.Context) (int, error) { return 3, nil })
+	}()
+	assert.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.m["key"] != nil && g.m["key"].dups == 1
+	}, time.Second, time.Millisecond)
+
+	// 结果被共享了，不能忘记
+	assert.False(t, g.ForgetUnshared("key"))
+	close(release)
+	<-done
+}
+
+func panickingFn(ctx context.Context) (int, error) {
+	panic("虾米诺手")
+}
+
+func TestGroupPanic(t *testing.T) {
+	var g Group[string, int]
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				r := recover()
+				pe, ok := r.(*PanicError)
+				assert.True(t, ok)
+				assert.Equal(t, "虾米诺手", pe.Value)
+				// 带有 fn 所在 goroutine 的调用栈
+				assert.Contains(t, string(pe.Stack), "panickingFn")
+			}()
+			_, _, _ = g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+				time.Sleep(10 * time.Millisecond)
+				return panickingFn(ctx)
+			})
+		}()
+	}
+	wg.Wait()
+
+	// panic 的结果不会被保留
+	v, err, _ := g.Do(context.Background(), "key", func(ctx context.Context) (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestGroupGoexit(t *testing.T) {
+	var g Group[string, int]
+	_, err, _ := g.Do(context.Background(), "key", func(ctx context.Context) (int, error) {
+		runtime.Goexit()
+		return 0, nil
+	})
+	assert.ErrorIs(t, err, ErrGoexit)
+}
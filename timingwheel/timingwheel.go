@@ -0,0 +1,220 @@
+package timingwheel
+
+import (
+	"sync"
+	"time"
+
+	"concurrence/clock"
+)
+
+// 大量定时器时，time.AfterFunc 和 schedgroup 的 Scheduler 都用堆，每次添加和撤销都是 O(log n)
+// 时间轮把时间分成固定长度的 tick，每一格（slot）是一个定时器链表，添加和撤销只需要挂到链表上或者从链表上摘下来，是 O(1)
+// 一层时间轮只能表示 slots 个 tick，更远的定时器放到上一层，上一层的一格等于下一层转一圈，
+// 需要更多层时自动添加（overflow wheel）；时间每走过上一层的一格，就把那一格的定时器重新放到下面的层（cascade）
+// 代价是精度只有一个 tick：定时器不会提前触发，最多晚一个 tick
+
+// Option 时间轮的选项
+type Option func(*options)
+
+type options struct {
+	clock clock.Clock
+}
+
+// WithClock 设置使用的时钟，测试时可以传入 clock.Fake
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// TimingWheel 分层时间轮
+type TimingWheel struct {
+	tick  time.Duration
+	slots int64
+	clock clock.Clock
+	start time.Time
+	done  chan struct{}
+	stop  sync.Once
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	now    int64    // 已经处理到的 tick
+	levels []*level // levels[0] 的一格是一个 tick
+}
+
+// level 一层时间轮
+type level struct {
+	unit    int64 // 一格是多少个 tick，slots 的 n 次方
+	span    int64 // 一圈是多少个 tick
+	buckets []bucket
+}
+
+// bucket 一格，用 Timer 自身做节点的双向循环链表
+type bucket struct {
+	head Timer
+}
+
+// Timer 时间轮上的定时器
+type Timer struct {
+	w    *TimingWheel
+	f    func()
+	exp  int64 // 在第几个 tick 触发
+	prev *Timer
+	next *Timer
+	b    *bucket // 所在的格，为 nil 表示已经触发或者撤销
+}
+
+// New 创建一个时间轮，每 tick 走一格，每层有 slots 格
+func New(tick time.Duration, slots int, opts ...Option) *TimingWheel {
+	if tick <= 0 {
+		panic("timingwheel: tick must be positive")
+	}
+	if slots < 2 {
+		panic("timingwheel: slots must be at least 2")
+	}
+	o := options{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := &TimingWheel{
+		tick:  tick,
+		slots: int64(slots),
+		clock: o.clock,
+		start: o.clock.Now(),
+		done:  make(chan struct{}),
+	}
+	w.addLevel()
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// AfterFunc d 之后在新的 goroutine 中执行 f，和 time.AfterFunc 一样
+func (w *TimingWheel) AfterFunc(d time.Duration, f func()) *Timer {
+	// 向上取整，保证不会提前触发
+	elapsed := w.clock.Since(w.start) + d
+	exp := int64((elapsed + w.tick - 1) / w.tick)
+
+	t := &Timer{w: w, f: f}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// 当前 tick 已经处理过了，最早在下一个 tick 触发
+	t.exp = max(exp, w.now+1)
+	w.add(t)
+	return t
+}
+
+// Stop 停止时间轮，还没触发的定时器不再触发，可以多次调用
+func (w *TimingWheel) Stop() {
+	w.stop.Do(func() { close(w.done) })
+	w.wg.Wait()
+}
+
+// Stop 撤销定时器，已经触发或者已经撤销时返回 false
+func (t *Timer) Stop() bool {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	if t.b == nil {
+		return false
+	}
+	t.unlink()
+	return true
+}
+
+func (w *TimingWheel) run() {
+	defer w.wg.Done()
+
+	timer := w.clock.NewTimer(w.tick)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+		case <-w.done:
+			return
+		}
+
+		// 按时钟追上落后的 tick，而不是每次只走一格，避免调度延迟累积
+		target := int64(w.clock.Since(w.start) / w.tick)
+		w.mu.Lock()
+		for w.now < target {
+			for _, t := range w.advance() {
+				go t.f()
+			}
+		}
+		next := w.start.Add(time.Duration(w.now+1) * w.tick)
+		w.mu.Unlock()
+
+		timer.Reset(next.Sub(w.clock.Now()))
+	}
+}
+
+// advance 走一格，返回到期的定时器，调用时需要持有 w.mu
+func (w *TimingWheel) advance() []*Timer {
+	w.now++
+
+	// 走过上层的一格时，把那一格的定时器放到下面的层
+	for _, l := range w.levels[1:] {
+		if w.now%l.unit != 0 {
+			break
+		}
+		b := &l.buckets[(w.now/l.unit)%w.slots]
+		for t := b.head.next; t != &b.head; {
+			next := t.next
+			t.unlink()
+			w.add(t)
+			t = next
+		}
+	}
+
+	b := &w.levels[0].buckets[w.now%w.slots]
+	var expired []*Timer
+	for t := b.head.next; t != &b.head; {
+		next := t.next
+		t.unlink()
+		expired = append(expired, t)
+		t = next
+	}
+	return expired
+}
+
+// add 把定时器放到对应的层和格，调用时需要持有 w.mu
+func (w *TimingWheel) add(t *Timer) {
+	delta := t.exp - w.now
+	for i := 0; ; i++ {
+		if i == len(w.levels) {
+			w.addLevel()
+		}
+		if l := w.levels[i]; delta < l.span {
+			t.link(&l.buckets[(t.exp/l.unit)%w.slots])
+			return
+		}
+	}
+}
+
+func (w *TimingWheel) addLevel() {
+	unit := int64(1)
+	if n := len(w.levels); n > 0 {
+		unit = w.levels[n-1].span
+	}
+	l := &level{unit: unit, span: unit * w.slots, buckets: make([]bucket, w.slots)}
+	for i := range l.buckets {
+		h := &l.buckets[i].head
+		h.prev, h.next = h, h
+	}
+	w.levels = append(w.levels, l)
+}
+
+func (t *Timer) link(b *bucket) {
+	h := &b.head
+	t.prev, t.next = h.prev, h
+	h.prev.next = t
+	h.prev = t
+	t.b = b
+}
+
+func (t *Timer) unlink() {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev, t.next, t.b = nil, nil, nil
+}
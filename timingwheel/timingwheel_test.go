@@ -0,0 +1,204 @@
+package timingwheel
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"concurrence/clock"
+	"concurrence/group/schedgroup"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// step 走 n 格，返回每个定时器在第几格触发
+func (w *TimingWheel) step(n int) map[*Timer]int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fired := map[*Timer]int64{}
+	for range n {
+		for _, t := range w.advance() {
+			fired[t] = w.now
+		}
+	}
+	return fired
+}
+
+func TestTimingWheelAccuracy(t *testing.T) {
+	// 不拨动时钟，直接调用 advance，检查每个定时器都在对应的 tick 触发
+	c := clock.NewFake(epoch)
+	w := New(time.Millisecond, 8, WithClock(c))
+	defer w.Stop()
+
+	const n, horizon = 2000, 5000
+	r := rand.New(rand.NewPCG(1, 2))
+	want := map[*Timer]int64{}
+	for range n {
+		d := time.Duration(r.IntN(horizon*1000)+1) * time.Microsecond
+		tm := w.AfterFunc(d, func() {})
+		// 向上取整到 tick
+		want[tm] = int64((d + time.Millisecond - 1) / time.Millisecond)
+	}
+	// 8 格一层，5000 个 tick 需要 5 层
+	assert.Len(t, w.levels, 5)
+
+	// 中途撤销一部分
+	stopped := 0
+	for tm := range want {
+		if stopped == n/4 {
+			break
+		}
+		assert.True(t, tm.Stop())
+		assert.False(t, tm.Stop())
+		delete(want, tm)
+		stopped++
+	}
+
+	got := w.step(horizon)
+	assert.Equal(t, want, got)
+}
+
+func TestTimingWheelAddWhileRunning(t *testing.T) {
+	c := clock.NewFake(epoch)
+	w := New(time.Millisecond, 4, WithClock(c))
+	defer w.Stop()
+
+	// 时间轮已经走了一段，再添加的定时器仍然准确
+	w.step(37)
+	c.Set(epoch.Add(37 * time.Millisecond))
+	want := map[*Timer]int64{}
+	for d := range 100 {
+		want[w.AfterFunc(time.Duration(d)*time.Millisecond, func() {})] = 37 + max(int64(d), 1)
+	}
+	assert.Equal(t, want, w.step(100))
+}
+
+func TestTimingWheelAfterFunc(t *testing.T) {
+	c := clock.NewFake(epoch)
+	w := New(10*time.Millisecond, 16, WithClock(c))
+	defer w.Stop()
+
+	var fired atomic.Int32
+	w.AfterFunc(25*time.Millisecond, func() { fired.Add(1) })
+	stopped := w.AfterFunc(25*time.Millisecond, func() { fired.Add(10) })
+	assert.True(t, stopped.Stop())
+
+	// 不会提前触发
+	c.BlockUntil(1)
+	c.Advance(20 * time.Millisecond)
+	c.BlockUntil(1)
+	assert.Equal(t, int32(0), fired.Load())
+
+	c.Advance(10 * time.Millisecond)
+	assert.Eventually(t, func() bool { return fired.Load() == 1 }, time.Second, time.Millisecond)
+
+	// 时钟跳过很多 tick 时一次追上
+	for range 100 {
+		w.AfterFunc(time.Second, func() { fired.Add(1) })
+	}
+	c.BlockUntil(1)
+	c.Advance(time.Hour)
+	assert.Eventually(t, func() bool { return fired.Load() == 101 }, time.Second, time.Millisecond)
+}
+
+func TestTimingWheelRealClock(t *testing.T) {
+	w := New(time.Millisecond, 64)
+	defer w.Stop()
+
+	start := time.Now()
+	done := make(chan time.Duration, 1)
+	w.AfterFunc(30*time.Millisecond, func() { done <- time.Since(start) })
+	assert.GreaterOrEqual(t, <-done, 30*time.Millisecond)
+}
+
+func TestTimingWheelStop(t *testing.T) {
+	w := New(time.Millisecond, 64)
+	var fired atomic.Bool
+	w.AfterFunc(10*time.Millisecond, func() { fired.Store(true) })
+
+	// 可以多次调用
+	w.Stop()
+	w.Stop()
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, fired.Load())
+}
+
+// 1M 个等待中的定时器时，添加并撤销一个定时器的开销
+
+const pending = 1_000_000
+
+func BenchmarkAfterFuncStop(b *testing.B) {
+	b.Run("TimingWheel", func(b *testing.B) {
+		w := New(time.Millisecond, 256)
+		defer w.Stop()
+		for range pending {
+			w.AfterFunc(time.Hour, func() {})
+		}
+		b.ResetTimer()
+		for range b.N {
+			w.AfterFunc(time.Minute, func() {}).Stop()
+		}
+	})
+
+	b.Run("time.AfterFunc", func(b *testing.B) {
+		timers := make([]*time.Timer, 0, pending)
+		for range pending {
+			timers = append(timers, time.AfterFunc(time.Hour, func() {}))
+		}
+		defer func() {
+			for _, t := range timers {
+				t.Stop()
+			}
+		}()
+		b.ResetTimer()
+		for range b.N {
+			time.AfterFunc(time.Minute, func() {}).Stop()
+		}
+	})
+
+	b.Run("Scheduler", func(b *testing.B) {
+		s := schedgroup.NewScheduler(context.Background())
+		defer s.Stop()
+		for range pending {
+			s.Delay(time.Hour, func() {})
+		}
+		b.ResetTimer()
+		for range b.N {
+			s.Delay(time.Minute, func() {}).Cancel()
+		}
+	})
+}
+
+func BenchmarkAfterFunc(b *testing.B) {
+	// 只添加，b.N 个定时器都在等待
+	b.Run("TimingWheel", func(b *testing.B) {
+		w := New(time.Millisecond, 256)
+		defer w.Stop()
+		for range b.N {
+			w.AfterFunc(time.Hour, func() {})
+		}
+	})
+
+	b.Run("time.AfterFunc", func(b *testing.B) {
+		timers := make([]*time.Timer, 0, b.N)
+		for range b.N {
+			timers = append(timers, time.AfterFunc(time.Hour, func() {}))
+		}
+		b.StopTimer()
+		for _, t := range timers {
+			t.Stop()
+		}
+	})
+
+	b.Run("Scheduler", func(b *testing.B) {
+		s := schedgroup.NewScheduler(context.Background())
+		defer s.Stop()
+		for range b.N {
+			s.Delay(time.Hour, func() {})
+		}
+	})
+}